@@ -0,0 +1,90 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/ginserver/auth"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type storedKey struct {
+	key  shared.APIKey
+	salt []byte
+	hash []byte
+}
+
+type memoryKeyStore map[string]storedKey
+
+func (s memoryKeyStore) Authenticate(_ context.Context, key string) (*shared.APIKey, error) {
+	id, secret, ok := shared.ParseAPIKey(key)
+	if !ok {
+		return nil, shared.ErrInvalidAPIKey
+	}
+	if id == "broken" {
+		return nil, errors.New("database is down")
+	}
+	stored, ok := s[id]
+	if !ok || !shared.VerifyAPIKey(secret, stored.salt, stored.hash) {
+		return nil, shared.ErrInvalidAPIKey
+	}
+	return &stored.key, nil
+}
+
+func (s memoryKeyStore) add(t *testing.T, key shared.APIKey) string {
+	t.Helper()
+	raw, id, secret, err := shared.GenerateAPIKey("test")
+	require.NoError(t, err)
+	salt, err := shared.NewAPIKeySalt()
+	require.NoError(t, err)
+	key.ID = id
+	s[id] = storedKey{key: key, salt: salt, hash: shared.HashAPIKey(secret, salt)}
+	return raw
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	store := memoryKeyStore{}
+	valid := store.add(t, shared.APIKey{Subject: "billing", Scopes: []string{"invoices:read"}})
+	expired := store.add(t, shared.APIKey{Subject: "old", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware(), auth.APIKeyMiddleware(store))
+	router.GET("/invoices", auth.RequireScopes("invoices:read"), func(c *gin.Context) {
+		key, ok := auth.GetAPIKey(c)
+		require.True(t, ok)
+		c.String(http.StatusOK, key.ID+" "+c.GetString(shared.SubjectContextKey))
+	})
+	router.GET("/payments", auth.RequireScopes("payments:read"), ok)
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	id, _, _ := shared.ParseAPIKey(valid)
+	for _, header := range [][]string{{"X-API-Key", valid}, {"Authorization", "ApiKey " + valid}} {
+		w := get("/invoices", header...)
+		require.Equal(t, http.StatusOK, w.Code, header[0])
+		require.Equal(t, id+" billing", w.Body.String())
+	}
+
+	require.Equal(t, http.StatusForbidden, get("/payments", "X-API-Key", valid).Code)
+	require.Equal(t, http.StatusUnauthorized, get("/invoices").Code)
+	require.Equal(t, http.StatusUnauthorized, get("/invoices", "X-API-Key", expired).Code)
+	require.Equal(t, http.StatusUnauthorized, get("/invoices", "X-API-Key", id+".wrong").Code)
+	require.Equal(t, http.StatusUnauthorized, get("/invoices", "Authorization", "Bearer "+valid).Code)
+	require.Equal(t, http.StatusInternalServerError, get("/invoices", "X-API-Key", "broken.x").Code)
+}
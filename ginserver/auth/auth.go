@@ -0,0 +1,251 @@
+// Package auth authenticates requests with JWT bearer tokens, see Middleware.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config of a verifier, see FromConfig.
+type Config struct {
+	// expected iss claim, also used for OpenID Connect discovery without JWKSURL and JWKSFile
+	Issuer string `envconfig:"AUTH_ISSUER"`
+	// accepted aud claims, tokens must have one of them
+	Audience []string `envconfig:"AUTH_AUDIENCE"`
+	// JSON Web Key Set URL
+	JWKSURL string `envconfig:"AUTH_JWKS_URL"`
+	// JSON Web Key Set file, e.g. for offline tests
+	JWKSFile string `envconfig:"AUTH_JWKS_FILE"`
+	// tolerated clock skew when checking exp, nbf and iat
+	Leeway   time.Duration `envconfig:"AUTH_LEEWAY" default:"30s"`
+	CacheTTL time.Duration `envconfig:"AUTH_JWKS_CACHE_TTL" default:"1h"`
+}
+
+// FromConfig creates a verifier with the key source of config:
+// JWKSFile, JWKSURL or the OpenID Connect discovery of Issuer.
+func FromConfig(config Config, opts ...Option) (*Verifier, error) {
+	var keys KeySource
+	switch {
+	case config.JWKSFile != "":
+		fileKeys, err := JWKSFile(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = fileKeys
+	case config.JWKSURL != "":
+		keys = NewJWKS(config.JWKSURL, WithCacheTTL(config.CacheTTL))
+	case config.Issuer != "":
+		keys = NewOIDC(config.Issuer, WithCacheTTL(config.CacheTTL))
+	default:
+		return nil, errors.New("auth: AUTH_JWKS_FILE, AUTH_JWKS_URL or AUTH_ISSUER is required")
+	}
+
+	return NewVerifier(keys, append([]Option{
+		WithIssuer(config.Issuer),
+		WithAudience(config.Audience...),
+		WithLeeway(config.Leeway),
+	}, opts...)...), nil
+}
+
+type options struct {
+	issuer     string
+	audience   []string
+	leeway     time.Duration
+	algorithms []string
+	optional   bool
+	check      func(c *gin.Context, claims *Claims) error
+}
+
+// Option configures a Verifier.
+type Option func(*options)
+
+// WithIssuer requires the iss claim to be issuer.
+func WithIssuer(issuer string) Option {
+	return func(o *options) {
+		o.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain one of audience.
+func WithAudience(audience ...string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// WithLeeway sets the tolerated clock skew, defaults to 30s.
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *options) {
+		o.leeway = leeway
+	}
+}
+
+// WithAlgorithms sets the accepted signing algorithms, defaults to the
+// RSA, RSA-PSS, ECDSA and EdDSA ones. List HS256 to verify tokens with a []byte secret.
+func WithAlgorithms(algorithms ...string) Option {
+	return func(o *options) {
+		o.algorithms = algorithms
+	}
+}
+
+// WithOptional lets requests without token through, anonymously.
+// Requests with an invalid token are still rejected.
+func WithOptional() Option {
+	return func(o *options) {
+		o.optional = true
+	}
+}
+
+// WithCheck runs check on the claims of verified tokens. Its errors are
+// shared.ErrPermissionDenied unless they are shared.ErrUnauthorized.
+func WithCheck(check func(c *gin.Context, claims *Claims) error) Option {
+	return func(o *options) {
+		o.check = check
+	}
+}
+
+// Verifier verifies tokens.
+type Verifier struct {
+	keys    KeySource
+	options options
+	parser  *jwt.Parser
+}
+
+// NewVerifier creates a verifier of tokens signed by keys.
+func NewVerifier(keys KeySource, opts ...Option) *Verifier {
+	o := options{
+		leeway: 30 * time.Second,
+		algorithms: []string{
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+			"EdDSA",
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(o.algorithms),
+		jwt.WithLeeway(o.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if o.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(o.issuer))
+	}
+	return &Verifier{keys: keys, options: o, parser: jwt.NewParser(parserOpts...)}
+}
+
+// Verify verifies the signature and the claims of token.
+// Errors are shared.ErrUnauthorized, or shared.ErrUnexpected when the key source fails
+// (other than with ErrKeyNotFound). Clients only see "invalid token", the cause is logged.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	var keyErr error
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.keys.Key(ctx, kid)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			keyErr = err
+		}
+		return key, err
+	})
+	if keyErr != nil {
+		return nil, shared.ErrUnexpected.Wrap(keyErr)
+	}
+	if err != nil {
+		return nil, invalidToken(err)
+	}
+	if len(v.options.audience) > 0 &&
+		!slices.ContainsFunc(claims.Audience, func(aud string) bool {
+			return slices.Contains(v.options.audience, aud)
+		}) {
+		return nil, invalidToken(fmt.Errorf("token has invalid audience %v", claims.Audience))
+	}
+	return claims, nil
+}
+
+func invalidToken(cause error) error {
+	return shared.NewError(shared.ErrUnauthorized, "", "invalid token").WithCause(cause)
+}
+
+// Middleware authenticates requests with their Authorization: Bearer token.
+// The claims are put in the gin context (GetClaims) and the request context (FromContext),
+// and the subject in shared.SubjectContextKey.
+//
+// Failures are added to the gin errors as shared.ErrUnauthorized, shared.ErrPermissionDenied or
+// shared.ErrUnexpected (key source failures) and the request is aborted with 401, 403 or 500, use ginserver.ErrorMiddleware to respond with problem details.
+func (v *Verifier) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			if v.options.optional {
+				c.Next()
+				return
+			}
+			c.Header("WWW-Authenticate", "Bearer")
+			abort(c, shared.NewError(shared.ErrUnauthorized, "", "missing bearer token"))
+			return
+		}
+
+		claims, err := v.Verify(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, shared.ErrUnauthorized) {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			abort(c, err)
+			return
+		}
+
+		if v.options.check != nil {
+			if err := v.options.check(c, claims); err != nil {
+				abort(c, permissionDenied(err))
+				return
+			}
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Set(shared.SubjectContextKey, claims.Subject)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+// Middleware authenticates requests with tokens signed by keys, see Verifier.Middleware.
+func Middleware(keys KeySource, opts ...Option) gin.HandlerFunc {
+	return NewVerifier(keys, opts...).Middleware()
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func permissionDenied(err error) error {
+	if errors.Is(err, shared.ErrUnauthorized) || errors.Is(err, shared.ErrPermissionDenied) {
+		return err
+	}
+	return shared.ErrPermissionDenied.Wrap(err)
+}
+
+// abort adds err to the gin errors for the error middleware,
+// the mapped status is the response without error middleware.
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+	c.Status(shared.HTTPErrors().Lookup(err).Status)
+}
@@ -0,0 +1,322 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/ginserver/auth"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const issuer = "https://issuer.example.com"
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":    issuer,
+		"sub":    "user-1",
+		"aud":    []string{"api"},
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Hour).Unix(),
+		"scope":  "read write",
+		"roles":  []string{"admin"},
+		"tenant": "acme",
+	}
+}
+
+func jwks(t *testing.T, keys map[string]*ecdsa.PrivateKey) []byte {
+	t.Helper()
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"use": "sig",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	b, err := json.Marshal(set)
+	require.NoError(t, err)
+	return b
+}
+
+func serve(t *testing.T, middleware gin.HandlerFunc, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware(), middleware)
+	router.GET("/me", func(c *gin.Context) {
+		claims, ok := auth.GetClaims(c)
+		require.True(t, ok)
+		fromCtx, ok := auth.FromContext(c.Request.Context())
+		require.True(t, ok)
+		require.Same(t, claims, fromCtx)
+		c.JSON(http.StatusOK, gin.H{
+			"sub":     c.GetString(shared.SubjectContextKey),
+			"scopes":  claims.Scopes(),
+			"admin":   claims.HasRole("admin"),
+			"tenant":  claims.Raw["tenant"],
+			"expires": claims.ExpiresAt.Unix(),
+		})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	key := newKey(t)
+	middleware := auth.Middleware(auth.StaticKey(&key.PublicKey),
+		auth.WithIssuer(issuer),
+		auth.WithAudience("api", "other"),
+		auth.WithLeeway(time.Minute))
+
+	t.Run("valid", func(t *testing.T) {
+		w := serve(t, middleware, sign(t, key, "", validClaims()))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"sub":"user-1"`)
+		require.Contains(t, w.Body.String(), `"scopes":["read","write"]`)
+		require.Contains(t, w.Body.String(), `"admin":true`)
+		require.Contains(t, w.Body.String(), `"tenant":"acme"`)
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+		require.Equal(t, http.StatusOK, serve(t, middleware, sign(t, key, "", claims)).Code)
+	})
+
+	invalid := map[string]func(jwt.MapClaims){
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"not yet valid":  func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(2 * time.Minute).Unix() },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "billing" },
+	}
+	for name, modify := range invalid {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			modify(claims)
+			w := serve(t, middleware, sign(t, key, "", claims))
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+			require.Equal(t, shared.ProblemContentType, w.Header().Get("Content-Type"))
+			require.Contains(t, w.Body.String(), `"code":"unauthorized"`)
+			require.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+
+	t.Run("wrong key", func(t *testing.T) {
+		w := serve(t, middleware, sign(t, newKey(t), "", validClaims()))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Contains(t, w.Body.String(), `"detail":"invalid token"`)
+	})
+
+	t.Run("key source failure", func(t *testing.T) {
+		middleware := auth.Middleware(auth.KeySourceFunc(func(context.Context, string) (crypto.PublicKey, error) {
+			return nil, errors.New("auth: fetch https://idp.internal/jwks: connection refused")
+		}))
+		w := serve(t, middleware, sign(t, key, "", validClaims()))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.NotContains(t, w.Body.String(), "idp.internal")
+		require.Empty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		w := serve(t, middleware, "")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("check", func(t *testing.T) {
+		middleware := auth.Middleware(auth.StaticKey(&key.PublicKey),
+			auth.WithCheck(func(_ *gin.Context, claims *auth.Claims) error {
+				if claims.Raw["tenant"] != "other" {
+					return errors.New("wrong tenant")
+				}
+				return nil
+			}))
+		w := serve(t, middleware, sign(t, key, "", validClaims()))
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Contains(t, w.Body.String(), `"code":"permission_denied"`)
+	})
+}
+
+func TestWithoutErrorMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(auth.Middleware(auth.StaticKey(&newKey(t).PublicKey)))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWKS(t *testing.T) {
+	key1, key2 := newKey(t), newKey(t)
+	var keys atomic.Value
+	keys.Store(jwks(t, map[string]*ecdsa.PrivateKey{"k1": key1}))
+	var fetches atomic.Int32
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = w.Write([]byte(`{"issuer":"` + server.URL + `","jwks_uri":"` + server.URL + `/jwks"}`))
+		case "/jwks":
+			fetches.Add(1)
+			_, _ = w.Write(keys.Load().([]byte))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	source := auth.NewOIDC(server.URL, auth.WithRefreshInterval(0))
+	ctx := context.Background()
+
+	got, err := source.Key(ctx, "k1")
+	require.NoError(t, err)
+	require.True(t, key1.PublicKey.Equal(got))
+
+	// cached
+	_, err = source.Key(ctx, "k1")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), fetches.Load())
+
+	// rotated keys are fetched on unknown kid
+	keys.Store(jwks(t, map[string]*ecdsa.PrivateKey{"k1": key1, "k2": key2}))
+	got, err = source.Key(ctx, "k2")
+	require.NoError(t, err)
+	require.True(t, key2.PublicKey.Equal(got))
+	require.Equal(t, int32(2), fetches.Load())
+
+	_, err = source.Key(ctx, "k3")
+	require.ErrorIs(t, err, auth.ErrKeyNotFound)
+
+	claims := validClaims()
+	claims["iss"] = server.URL
+	w := serve(t, auth.Middleware(source, auth.WithIssuer(server.URL)), sign(t, key2, "k2", claims))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWKSFailures(t *testing.T) {
+	key1 := newKey(t)
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			<-release
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(jwks(t, map[string]*ecdsa.PrivateKey{"k1": key1}))
+	}))
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	source := auth.NewJWKS(server.URL, auth.WithRefreshInterval(50*time.Millisecond))
+	_, err := source.Key(ctx, "k1")
+	require.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+
+	failing.Store(true)
+	refreshed := make(chan error)
+	go func() {
+		_, err := source.Key(ctx, "k2")
+		refreshed <- err
+	}()
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, time.Millisecond)
+
+	// the cached keys are served during the fetch, which is not repeated
+	got, err := source.Key(ctx, "k1")
+	require.NoError(t, err)
+	require.True(t, key1.PublicKey.Equal(got))
+	_, err = source.Key(ctx, "k3")
+	require.ErrorIs(t, err, auth.ErrKeyNotFound)
+
+	close(release)
+	require.ErrorIs(t, <-refreshed, auth.ErrKeyNotFound)
+	// failed fetches are retried after the refresh interval
+	_, err = source.Key(ctx, "k2")
+	require.ErrorIs(t, err, auth.ErrKeyNotFound)
+	_, err = source.Key(ctx, "k1")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fetches.Load())
+
+	source = auth.NewJWKS(server.URL, auth.WithRefreshInterval(time.Hour))
+	for range 2 {
+		_, err = source.Key(ctx, "k1")
+		require.ErrorContains(t, err, "503")
+	}
+	require.Equal(t, int32(3), fetches.Load())
+}
+
+func TestFromConfig(t *testing.T) {
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, map[string]*ecdsa.PrivateKey{"k1": key}), 0o600))
+
+	verifier, err := auth.FromConfig(auth.Config{
+		Issuer:   issuer,
+		Audience: []string{"api"},
+		JWKSFile: path,
+		Leeway:   time.Second,
+	})
+	require.NoError(t, err)
+
+	claims, err := verifier.Verify(context.Background(), sign(t, key, "k1", validClaims()))
+	require.NoError(t, err)
+	require.Equal(t, "user-1", claims.Subject)
+
+	_, err = verifier.Verify(context.Background(), sign(t, key, "k2", validClaims()))
+	require.ErrorIs(t, err, shared.ErrUnauthorized)
+
+	_, err = auth.FromConfig(auth.Config{})
+	require.Error(t, err)
+}
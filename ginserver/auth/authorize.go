@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Policy decides whether the claims give access to resource, it returns nil to allow.
+// Errors are shared.ErrPermissionDenied unless they are shared.ErrUnauthorized or shared.ErrNotFound
+// (to hide the existence of the resource). Return a *shared.Error to send its message to clients.
+type Policy func(ctx context.Context, claims *Claims, resource any) error
+
+// ResourceFunc loads the resource a request targets, e.g. from its path parameters.
+type ResourceFunc func(c *gin.Context) (any, error)
+
+// RequireScopes allows requests whose token was granted all scopes.
+// Use it after Middleware.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	name := "scopes:" + strings.Join(scopes, " ")
+	return func(c *gin.Context) {
+		authorize(c, name, nil, func(_ context.Context, claims *Claims, _ any) error {
+			for _, scope := range scopes {
+				if !claims.HasScope(scope) {
+					c.Header("WWW-Authenticate",
+						fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, strings.Join(scopes, " ")))
+					return shared.NewError(shared.ErrPermissionDenied, "", "missing scope "+scope)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// RequireRole allows requests whose token has one of roles.
+// Use it after Middleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	name := "role:" + strings.Join(roles, ",")
+	return func(c *gin.Context) {
+		authorize(c, name, nil, func(_ context.Context, claims *Claims, _ any) error {
+			if slices.ContainsFunc(roles, claims.HasRole) {
+				return nil
+			}
+			return shared.NewError(shared.ErrPermissionDenied, "",
+				"one of roles "+strings.Join(roles, ", ")+" is required")
+		})
+	}
+}
+
+// Authorize allows requests for which policy returns nil. The resource, loaded by resource
+// (optional), is passed to the policy and put in the gin context under ResourceKey.
+// Use it after Middleware.
+func Authorize(name string, policy Policy, resource ResourceFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorize(c, name, resource, policy)
+	}
+}
+
+// ResourceKey is the gin context key of the resource loaded by Authorize.
+const ResourceKey = "auth_resource"
+
+// Check runs policy in a handler, once it loaded the resource.
+// It returns the error to respond with, the decision is recorded like with Authorize.
+func Check(c *gin.Context, name string, policy Policy, resource any) error {
+	claims, ok := GetClaims(c)
+	if !ok {
+		err := shared.NewError(shared.ErrUnauthorized, "", "request is not authenticated")
+		recordDecision(c.Request.Context(), name, nil, err)
+		return err
+	}
+	err := policy(c.Request.Context(), claims, resource)
+	if err != nil {
+		err = denied(err)
+	}
+	recordDecision(c.Request.Context(), name, claims, err)
+	return err
+}
+
+func authorize(c *gin.Context, name string, load ResourceFunc, policy Policy) {
+	var resource any
+	if load != nil {
+		var err error
+		if resource, err = load(c); err != nil {
+			abort(c, err)
+			return
+		}
+		c.Set(ResourceKey, resource)
+	}
+	if err := Check(c, name, policy, resource); err != nil {
+		abort(c, err)
+		return
+	}
+	c.Next()
+}
+
+func denied(err error) error {
+	if errors.Is(err, shared.ErrNotFound) {
+		return err
+	}
+	return permissionDenied(err)
+}
+
+// recordDecision adds the authorization decision to the span of the request.
+func recordDecision(ctx context.Context, name string, claims *Claims, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	decision := "allow"
+	if err != nil {
+		decision = "deny"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("auth.policy", name),
+		attribute.String("auth.decision", decision),
+	}
+	if claims != nil {
+		attrs = append(attrs, attribute.String("auth.subject", claims.Subject))
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("auth.reason", err.Error()))
+	}
+	span.SetAttributes(attrs[:2]...)
+	span.AddEvent("authorization", trace.WithAttributes(attrs...))
+}
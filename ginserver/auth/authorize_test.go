@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/ginserver/auth"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type document struct {
+	Owner string
+}
+
+func TestAuthorization(t *testing.T) {
+	key := newKey(t)
+	token := sign(t, key, "", validClaims())
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	ownerPolicy := func(_ context.Context, claims *auth.Claims, resource any) error {
+		if resource.(*document).Owner != claims.Subject {
+			return errors.New("not the owner")
+		}
+		return nil
+	}
+	loadDocument := func(c *gin.Context) (any, error) {
+		if c.Param("id") == "missing" {
+			return nil, shared.ErrNotFound
+		}
+		return &document{Owner: c.Param("id")}, nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx, span := tracer.Start(c.Request.Context(), c.Request.URL.Path)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	router.Use(ginserver.ErrorMiddleware())
+	router.GET("/anonymous", auth.RequireRole("admin"), ok)
+
+	authenticated := router.Group("/", auth.Middleware(auth.StaticKey(&key.PublicKey)))
+	authenticated.GET("/read", auth.RequireScopes("read"), ok)
+	authenticated.GET("/delete", auth.RequireScopes("read", "delete"), ok)
+	authenticated.GET("/admin", auth.RequireRole("owner", "admin"), ok)
+	authenticated.GET("/superuser", auth.RequireRole("superuser"), ok)
+	authenticated.GET("/documents/:id", auth.Authorize("document owner", ownerPolicy, loadDocument),
+		func(c *gin.Context) {
+			doc, _ := c.Get(auth.ResourceKey)
+			c.String(http.StatusOK, doc.(*document).Owner)
+		})
+	authenticated.GET("/check/:id", func(c *gin.Context) {
+		if err := auth.Check(c, "document owner", ownerPolicy, &document{Owner: c.Param("id")}); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if path != "/anonymous" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/anonymous", http.StatusUnauthorized},
+		{"/read", http.StatusOK},
+		{"/delete", http.StatusForbidden},
+		{"/admin", http.StatusOK},
+		{"/superuser", http.StatusForbidden},
+		{"/documents/user-1", http.StatusOK},
+		{"/documents/user-2", http.StatusForbidden},
+		{"/documents/missing", http.StatusNotFound},
+		{"/check/user-1", http.StatusOK},
+		{"/check/user-2", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := get(tt.path)
+			require.Equal(t, tt.status, w.Code)
+			if tt.status != http.StatusOK {
+				require.Equal(t, shared.ProblemContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	t.Run("insufficient scope", func(t *testing.T) {
+		w := get("/delete")
+		require.Equal(t, `Bearer error="insufficient_scope", scope="read delete"`, w.Header().Get("WWW-Authenticate"))
+		require.Contains(t, w.Body.String(), "missing scope delete")
+	})
+
+	t.Run("span", func(t *testing.T) {
+		decisions := map[string]string{}
+		for _, span := range recorder.Ended() {
+			for _, attr := range span.Attributes() {
+				if attr.Key == "auth.decision" {
+					decisions[span.Name()] = attr.Value.AsString()
+				}
+			}
+		}
+		require.Equal(t, "allow", decisions["/documents/user-1"])
+		require.Equal(t, "deny", decisions["/documents/user-2"])
+		require.Equal(t, "deny", decisions["/anonymous"])
+		require.NotContains(t, decisions, "/documents/missing")
+
+		for _, span := range recorder.Ended() {
+			if span.Name() != "/superuser" {
+				continue
+			}
+			require.Len(t, span.Events(), 1)
+			require.Contains(t, span.Events()[0].Attributes,
+				attribute.String("auth.policy", "role:superuser"))
+			require.Contains(t, span.Events()[0].Attributes,
+				attribute.String("auth.subject", "user-1"))
+		}
+	})
+}
+
+func ok(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClaimsKey is the gin context key of the *Claims of the request.
+const ClaimsKey = "auth_claims"
+
+type claimsContextKey struct{}
+
+// Claims of a verified token.
+type Claims struct {
+	jwt.RegisteredClaims
+	// space separated scopes (RFC 8693)
+	Scope string   `json:"scope,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	Email string   `json:"email,omitempty"`
+
+	// all the claims of the token, including custom ones
+	Raw map[string]any `json:"-"`
+}
+
+func (c *Claims) UnmarshalJSON(b []byte) error {
+	type plain Claims
+	if err := json.Unmarshal(b, (*plain)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &c.Raw)
+}
+
+// Scopes returns the scopes of the scope claim.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the token was granted scope.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// HasRole reports whether role is in the roles claim.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// GetClaims returns the claims set by Middleware.
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
+
+// FromContext returns the claims of the request of ctx, e.g. in services called by handlers.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
+
+// NewContext returns a copy of ctx carrying claims.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned by key sources without key for the kid of a token.
+var ErrKeyNotFound = errors.New("auth: signing key not found")
+
+// KeySource returns the public key verifying tokens signed with the key kid.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// KeySourceFunc adapts a function to KeySource.
+type KeySourceFunc func(ctx context.Context, kid string) (crypto.PublicKey, error)
+
+func (f KeySourceFunc) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	return f(ctx, kid)
+}
+
+// StaticKeys returns keys by kid. A key with an empty kid verifies tokens without kid.
+type StaticKeys map[string]crypto.PublicKey
+
+func (keys StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// StaticKey verifies all tokens with key, e.g. in tests.
+func StaticKey(key crypto.PublicKey) KeySource {
+	return KeySourceFunc(func(context.Context, string) (crypto.PublicKey, error) {
+		return key, nil
+	})
+}
+
+// JWKSFile reads the keys of a JSON Web Key Set file.
+func JWKSFile(path string) (StaticKeys, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	return ParseJWKS(b)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses the RSA, EC and Ed25519 signing keys of a JSON Web Key Set.
+// Keys of other types or for encryption are ignored.
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: invalid JWKS: %w", err)
+	}
+	keys := StaticKeys{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid JWK %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+type jwksConfig struct {
+	client          *http.Client
+	ttl             time.Duration
+	refreshInterval time.Duration
+}
+
+// JWKSOption configures a JWKS.
+type JWKSOption func(*jwksConfig)
+
+// WithHTTPClient sets the client fetching keys, defaults to a client with a 10s timeout.
+func WithHTTPClient(client *http.Client) JWKSOption {
+	return func(c *jwksConfig) {
+		c.client = client
+	}
+}
+
+// WithCacheTTL sets how long keys are cached, defaults to 1h.
+func WithCacheTTL(ttl time.Duration) JWKSOption {
+	return func(c *jwksConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithRefreshInterval sets the minimum interval between fetches triggered
+// by unknown kids (key rotations) or retrying failed fetches, defaults to 1m.
+func WithRefreshInterval(interval time.Duration) JWKSOption {
+	return func(c *jwksConfig) {
+		c.refreshInterval = interval
+	}
+}
+
+// JWKS fetches and caches the keys of a JSON Web Key Set URL.
+// Keys are fetched again when the cache expires or a token has an unknown kid,
+// one fetch at a time: the cached keys are served meanwhile, and while the URL fails.
+type JWKS struct {
+	config jwksConfig
+	// returns the URL of the key set, resolved once
+	url func(ctx context.Context) (string, error)
+
+	mu      sync.Mutex
+	keys    StaticKeys
+	fetched time.Time
+	// time and error of the last fetch, failed or not
+	attempted time.Time
+	err       error
+	// closed when the fetch in flight is done, nil without fetch
+	fetching chan struct{}
+}
+
+// NewJWKS creates a JWKS fetching url.
+func NewJWKS(url string, opts ...JWKSOption) *JWKS {
+	return newJWKS(func(context.Context) (string, error) { return url, nil }, opts)
+}
+
+// NewOIDC creates a JWKS fetching the jwks_uri of the OpenID Connect discovery
+// document of issuer (issuer + /.well-known/openid-configuration).
+// Discovery happens on the first verification, not at startup.
+func NewOIDC(issuer string, opts ...JWKSOption) *JWKS {
+	var j *JWKS
+	var mu sync.Mutex
+	var jwksURI string
+	j = newJWKS(func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if jwksURI != "" {
+			return jwksURI, nil
+		}
+		uri, err := j.discover(ctx, issuer)
+		if err != nil {
+			return "", err
+		}
+		jwksURI = uri
+		return jwksURI, nil
+	}, opts)
+	return j
+}
+
+func newJWKS(url func(context.Context) (string, error), opts []JWKSOption) *JWKS {
+	config := jwksConfig{
+		client:          &http.Client{Timeout: 10 * time.Second},
+		ttl:             time.Hour,
+		refreshInterval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &JWKS{config: config, url: url}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	done, fetch := j.fetching, false
+	if done == nil && j.stale(kid, time.Now()) {
+		done, fetch = make(chan struct{}), true
+		j.fetching = done
+	}
+	cached := j.keys != nil
+	j.mu.Unlock()
+
+	switch {
+	case fetch:
+		// the fetch is shared by the requests waiting for it, it is not canceled with this one
+		j.refresh(context.WithoutCancel(ctx), done)
+	case done != nil && !cached:
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.keys == nil && j.err != nil {
+		return nil, j.err
+	}
+	return j.keys.Key(ctx, kid)
+}
+
+// stale reports whether the keys must be fetched to verify kid.
+// Fetches are not retried before the refresh interval, failed ones neither.
+func (j *JWKS) stale(kid string, now time.Time) bool {
+	if now.Sub(j.attempted) < j.config.refreshInterval {
+		return false
+	}
+	if j.keys == nil || now.Sub(j.fetched) > j.config.ttl {
+		return true
+	}
+	_, ok := j.keys[kid]
+	return !ok
+}
+
+// refresh fetches the keys without holding the lock and closes done.
+func (j *JWKS) refresh(ctx context.Context, done chan struct{}) {
+	keys, err := j.fetch(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attempted = time.Now()
+	j.err = err
+	if err == nil {
+		j.keys = keys
+		j.fetched = j.attempted
+	}
+	j.fetching = nil
+	close(done)
+}
+
+func (j *JWKS) fetch(ctx context.Context) (StaticKeys, error) {
+	url, err := j.url(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := j.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(b)
+}
+
+func (j *JWKS) discover(ctx context.Context, issuer string) (string, error) {
+	b, err := j.get(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	var doc struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("auth: invalid OpenID configuration: %w", err)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("auth: OpenID configuration without jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
+
+func (j *JWKS) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := j.config.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetch %s: %w", url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: fetch %s: %s", url, res.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetch %s: %w", url, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,243 @@
+package ginserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+)
+
+// BindOptionsKey is the gin context key of the options set by BindMiddleware.
+const BindOptionsKey = "bind_options"
+
+// DefaultMaxBodySize is the max size of request bodies read by Bind, unless configured.
+const DefaultMaxBodySize = 1 << 20
+
+type bindConfig struct {
+	maxBodySize           int64
+	disallowUnknownFields bool
+}
+
+// BindOption configures Bind.
+type BindOption func(*bindConfig)
+
+// WithMaxBodySize sets the max size of the request body in bytes, 0 for unlimited.
+// Larger bodies fail with shared.ErrRequestTooLarge. Defaults to DefaultMaxBodySize.
+func WithMaxBodySize(size int64) BindOption {
+	return func(c *bindConfig) {
+		c.maxBodySize = size
+	}
+}
+
+// WithDisallowUnknownFields rejects bodies with fields that are not in the request struct.
+func WithDisallowUnknownFields(disallow bool) BindOption {
+	return func(c *bindConfig) {
+		c.disallowUnknownFields = disallow
+	}
+}
+
+// BindMiddleware sets the default options of Bind for the requests.
+func BindMiddleware(opts ...BindOption) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(BindOptionsKey, opts)
+		c.Next()
+	}
+}
+
+func newBindConfig(c *gin.Context, opts []BindOption) *bindConfig {
+	config := &bindConfig{maxBodySize: DefaultMaxBodySize}
+	if defaults, ok := c.Get(BindOptionsKey); ok {
+		if defaults, ok := defaults.([]BindOption); ok {
+			// the defaults are shared by the requests, never append to them
+			opts = slices.Concat(defaults, opts)
+		}
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Bind decodes the JSON body of the request into a T and validates it, see Validate.
+// Malformed bodies fail with a *shared.ValidationError, invalid ones with the validator
+// errors wrapped in shared.ErrValidationFailed; both are written by ErrorMiddleware.
+//
+//	req, err := ginserver.Bind[CreateUserRequest](c)
+//	if err != nil {
+//		c.Error(err)
+//		return
+//	}
+func Bind[T any](c *gin.Context, opts ...BindOption) (T, error) {
+	var req T
+	if err := decodeJSON(c, &req, opts); err != nil {
+		return req, err
+	}
+	return req, Validate(req)
+}
+
+// decodeJSON decodes the JSON body of the request into v.
+func decodeJSON(c *gin.Context, v any, opts []BindOption) error {
+	config := newBindConfig(c, opts)
+
+	body := c.Request.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if config.maxBodySize > 0 {
+		body = http.MaxBytesReader(c.Writer, body, config.maxBodySize)
+	}
+	decoder := json.NewDecoder(body)
+	if config.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	language := shared.LanguageFromContext(c.Request.Context())
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err, language)
+	}
+	// the body is a single value, e.g. not {"a":1} garbage or {}{}
+	switch err := decoder.Decode(&json.RawMessage{}); {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return decodeError(err, language)
+	default:
+		return &shared.ValidationError{Details: []shared.ValidationDetail{
+			shared.NewValidationDetail("", "syntax", "JSON", language),
+		}}
+	}
+}
+
+// BindQuery binds the query parameters of the request into a T using form tags and validates it.
+func BindQuery[T any](c *gin.Context) (T, error) {
+	var req T
+	if err := c.ShouldBindQuery(&req); err != nil {
+		return req, bindError(err)
+	}
+	return req, Validate(req)
+}
+
+// BindURI binds the path parameters of the request into a T using uri tags and validates it.
+func BindURI[T any](c *gin.Context) (T, error) {
+	var req T
+	if err := c.ShouldBindUri(&req); err != nil {
+		return req, bindError(err)
+	}
+	return req, Validate(req)
+}
+
+// BindHeader binds the headers of the request into a T using header tags and validates it.
+func BindHeader[T any](c *gin.Context) (T, error) {
+	var req T
+	if err := c.ShouldBindHeader(&req); err != nil {
+		return req, bindError(err)
+	}
+	return req, Validate(req)
+}
+
+// Validate validates v with shared.Validator: structs with their validate tags,
+// slices, arrays and maps by diving into their elements.
+// Errors are wrapped in shared.ErrValidationFailed.
+func Validate(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var err error
+	switch value.Kind() {
+	case reflect.Struct:
+		err = shared.Validator().Struct(value.Interface())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		err = shared.Validator().Var(value.Interface(), "dive")
+	default:
+		return nil
+	}
+	if err != nil {
+		return shared.ErrValidationFailed.Wrap(err)
+	}
+	return nil
+}
+
+// bindError normalizes the errors of gin binding (which validates binding tags).
+func bindError(err error) error {
+	if errors.Is(err, shared.ErrValidationFailed) {
+		return err
+	}
+	return shared.ErrValidationFailed.Wrap(err)
+}
+
+// decodeError converts the errors of decoding a JSON body to a *shared.ValidationError
+// in language, or shared.ErrRequestTooLarge.
+func decodeError(err error, language string) error {
+	var (
+		maxBytesErr  *http.MaxBytesError
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		invalidError *json.InvalidUnmarshalError
+	)
+	var detail shared.ValidationDetail
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return shared.NewError(shared.ErrRequestTooLarge, "",
+			fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &invalidError):
+		return err
+	case errors.As(err, &typeErr):
+		detail = shared.NewValidationDetail(fieldPath(typeErr.Field), "type", jsonType(typeErr.Type), language)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &syntaxErr):
+		detail = shared.NewValidationDetail("", "syntax", "JSON", language)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		detail = shared.NewValidationDetail(field, "unknown", "", language)
+	default:
+		return shared.ErrValidationFailed.Wrap(err)
+	}
+	return &shared.ValidationError{Details: []shared.ValidationDetail{detail}}
+}
+
+// fieldPath formats the indexes of a field path of encoding/json like the validator,
+// items.0.sku becomes items[0].sku.
+func fieldPath(field string) string {
+	var b strings.Builder
+	for i, name := range strings.Split(field, ".") {
+		switch {
+		case name != "" && strings.Trim(name, "0123456789") == "":
+			b.WriteString("[" + name + "]")
+		case i > 0:
+			b.WriteString("." + name)
+		default:
+			b.WriteString(name)
+		}
+	}
+	return b.String()
+}
+
+// jsonType returns the name of the JSON type of t.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
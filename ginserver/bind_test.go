@@ -0,0 +1,188 @@
+package ginserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type orderItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type createOrder struct {
+	Customer string      `json:"customer" validate:"required"`
+	Items    []orderItem `json:"items" validate:"min=1,dive"`
+}
+
+type listOrders struct {
+	Status string `form:"status" validate:"omitempty,oneof=open closed"`
+	Limit  int    `form:"limit" validate:"max=50"`
+}
+
+type orderURI struct {
+	ID int64 `uri:"id" validate:"gt=0"`
+}
+
+type orderHeader struct {
+	Tenant string `header:"X-Tenant" validate:"required"`
+}
+
+func bindRouter(opts ...ginserver.BindOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware(), ginserver.BindMiddleware(opts...))
+	router.POST("/orders", func(c *gin.Context) {
+		req, err := ginserver.Bind[createOrder](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, req)
+	})
+	router.POST("/batch", func(c *gin.Context) {
+		req, err := ginserver.Bind[[]orderItem](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, req)
+	})
+	router.GET("/orders", func(c *gin.Context) {
+		req, err := ginserver.BindQuery[listOrders](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+	router.GET("/orders/:id", func(c *gin.Context) {
+		uri, err := ginserver.BindURI[orderURI](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		header, err := ginserver.BindHeader[orderHeader](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": uri.ID, "tenant": header.Tenant})
+	})
+	return router
+}
+
+type bindProblem struct {
+	Code   string                    `json:"code"`
+	Errors []shared.ValidationDetail `json:"errors"`
+}
+
+func doBind(t *testing.T, router *gin.Engine, method, target, body string) (*httptest.ResponseRecorder, bindProblem) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	p := bindProblem{}
+	if w.Code >= http.StatusBadRequest {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	}
+	return w, p
+}
+
+func TestBind(t *testing.T) {
+	router := bindRouter()
+
+	w, _ := doBind(t, router, http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":2}],"note":"x"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	t.Run("nested dives", func(t *testing.T) {
+		w, p := doBind(t, router, http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":1},{"quantity":0}]}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "validation_failed", p.Code)
+		require.Len(t, p.Errors, 2)
+		require.Equal(t, "/items/1/sku", p.Errors[0].JSONPointer)
+		require.Equal(t, "/items/1/quantity", p.Errors[1].JSONPointer)
+
+		w, p = doBind(t, router, http.MethodPost, "/batch", `[{"sku":"a","quantity":0}]`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "min", p.Errors[0].Rule)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		w, p := doBind(t, router, http.MethodPost, "/orders", `{"customer":`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "syntax", p.Errors[0].Rule)
+
+		w, p = doBind(t, router, http.MethodPost, "/orders", ``)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "syntax", p.Errors[0].Rule)
+
+		for _, body := range []string{`{"customer":"c1"} garbage`, `{"customer":"c1"}{}`, `{"customer":"c1"}}`} {
+			w, p = doBind(t, router, http.MethodPost, "/orders", body)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+			require.Equal(t, "syntax", p.Errors[0].Rule, body)
+		}
+
+		w, p = doBind(t, router, http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":"two"}]}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, shared.ValidationDetail{
+			Field:       "items[0].quantity",
+			JSONPointer: "/items/0/quantity",
+			Rule:        "type",
+			Param:       "integer",
+			Message:     "must be of type integer",
+		}, p.Errors[0])
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		router := bindRouter(ginserver.WithDisallowUnknownFields(true))
+		w, p := doBind(t, router, http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":1}],"note":"x"}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, []shared.ValidationDetail{{
+			Field:       "note",
+			JSONPointer: "/note",
+			Rule:        "unknown",
+			Message:     "is not allowed",
+		}}, p.Errors)
+	})
+
+	t.Run("max body size", func(t *testing.T) {
+		router := bindRouter(ginserver.WithMaxBodySize(16))
+		w, p := doBind(t, router, http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":1}]}`)
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		require.Equal(t, "request_too_large", p.Code)
+	})
+}
+
+func TestBindQueryURIHeader(t *testing.T) {
+	router := bindRouter()
+
+	w, _ := doBind(t, router, http.MethodGet, "/orders?status=open&limit=10", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"Status":"open","Limit":10}`, w.Body.String())
+
+	w, p := doBind(t, router, http.MethodGet, "/orders?status=lost&limit=100", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, p.Errors, 2)
+	require.Equal(t, "status", p.Errors[0].Field)
+
+	w, _ = doBind(t, router, http.MethodGet, "/orders?limit=ten", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = doBind(t, router, http.MethodGet, "/orders/42", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id":42,"tenant":"acme"}`, w.Body.String())
+
+	w, p = doBind(t, router, http.MethodGet, "/orders/0", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "gt", p.Errors[0].Rule)
+}
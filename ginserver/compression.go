@@ -0,0 +1,393 @@
+package ginserver
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content encodings.
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+)
+
+type encoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// encoderPools reuses the encoders of each encoding, zstd encoders are expensive to create.
+//
+//nolint:gochecknoglobals // sync.Pool is safe for concurrent use by multiple goroutines.
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip:   {New: func() any { return gzip.NewWriter(nil) }},
+	EncodingBrotli: {New: func() any { return brotli.NewWriter(nil) }},
+	EncodingZstd: {New: func() any {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil
+		}
+		return enc
+	}},
+}
+
+func newEncoder(encoding string, w io.Writer) (encoder, error) {
+	pool, ok := encoderPools[encoding]
+	if !ok {
+		return nil, errors.New("compression: unsupported encoding " + encoding)
+	}
+	enc, ok := pool.Get().(encoder)
+	if !ok {
+		return nil, errors.New("compression: cannot create a " + encoding + " encoder")
+	}
+	enc.Reset(w)
+	return enc, nil
+}
+
+// releaseEncoder closes enc and puts it back in its pool.
+func releaseEncoder(encoding string, enc encoder) {
+	enc.Close()
+	encoderPools[encoding].Put(enc)
+}
+
+type compressionConfig struct {
+	encodings    []string
+	minSize      int
+	contentTypes []string
+}
+
+// CompressionOption configures CompressionHandler.
+type CompressionOption func(*compressionConfig)
+
+// WithCompressionEncodings sets the supported encodings in order of preference,
+// defaults to zstd, br and gzip.
+func WithCompressionEncodings(encodings ...string) CompressionOption {
+	return func(c *compressionConfig) {
+		c.encodings = encodings
+	}
+}
+
+// WithCompressionMinSize sets the size under which responses are not compressed, defaults to 1024.
+func WithCompressionMinSize(size int) CompressionOption {
+	return func(c *compressionConfig) {
+		c.minSize = size
+	}
+}
+
+// WithCompressionContentTypes sets the media types that are compressed.
+// A type ending with /* matches all its subtypes, e.g. text/*.
+func WithCompressionContentTypes(types ...string) CompressionOption {
+	return func(c *compressionConfig) {
+		c.contentTypes = types
+	}
+}
+
+// CompressionHandler wraps the http.Handler h with response compression.
+// The encoding is negotiated with Accept-Encoding, and Vary: Accept-Encoding is always set.
+//
+// Wrap it around EtagHandler: strong etags of encoded responses get the
+// encoding as suffix ("...-gzip") and the suffix is removed from If-None-Match
+// and If-Match, so EtagHandler and CheckPreconditions see the etags of the identity representation.
+// Weak etags are kept as is.
+func CompressionHandler(h http.Handler, opts ...CompressionOption) http.Handler {
+	config := &compressionConfig{
+		encodings: []string{EncodingZstd, EncodingBrotli, EncodingGzip},
+		minSize:   1024,
+		contentTypes: []string{
+			"text/*",
+			"application/json",
+			ProblemContentType,
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+		},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		addVary(res.Header(), headers.AcceptEncoding)
+
+		// conditionals are stripped whatever the encoding of this response, e.g. a PUT
+		// without Accept-Encoding sends the etag of a gzip GET
+		encodedConditional := false
+		for _, name := range []string{headers.IfNoneMatch, headers.IfMatch} {
+			if v := req.Header.Get(name); v != "" {
+				stripped := stripEncodedEtags(v, config.encodings)
+				encodedConditional = encodedConditional || stripped != v
+				req.Header.Set(name, stripped)
+			}
+		}
+
+		encoding := negotiateEncoding(req.Header.Get(headers.AcceptEncoding), config.encodings)
+		if encoding == "" || req.Method == http.MethodHead {
+			h.ServeHTTP(res, req)
+			return
+		}
+
+		cw := &compressWriter{rw: res, config: config, encoding: encoding, encodedConditional: encodedConditional}
+
+		defer cw.finish()
+		h.ServeHTTP(cw, req)
+	})
+}
+
+const (
+	compressBuffering = iota
+	compressEncoding
+	compressPassthrough
+)
+
+type compressWriter struct {
+	rw       http.ResponseWriter
+	config   *compressionConfig
+	encoding string
+	status   int
+	buf      []byte
+	enc      encoder
+	state    int
+
+	// the client sent etags of an encoded representation
+	encodedConditional bool
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.rw.Header()
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	// informational responses are sent as is
+	if status < http.StatusOK {
+		cw.rw.WriteHeader(status)
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+
+	// responses without body
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		if status == http.StatusNotModified && cw.encodedConditional {
+			cw.setEtag()
+		}
+		cw.state = compressPassthrough
+		cw.rw.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch cw.state {
+	case compressPassthrough:
+		return cw.rw.Write(b)
+	case compressEncoding:
+		return cw.enc.Write(b)
+	default:
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) >= cw.config.minSize {
+			if err := cw.start(); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+}
+
+// Flush compresses (when eligible) and sends everything written so far.
+func (cw *compressWriter) Flush() {
+	if cw.state == compressBuffering {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if err := cw.start(); err != nil {
+			return
+		}
+	}
+	if cw.state == compressEncoding {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("compression: response writer does not implement http.Hijacker")
+	}
+	cw.state = compressPassthrough
+	return h.Hijack()
+}
+
+// Unwrap is used by http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.rw
+}
+
+// start decides whether to compress the response and writes the buffered body.
+func (cw *compressWriter) start() error {
+	header := cw.Header()
+	if header.Get(headers.ContentEncoding) != "" ||
+		header.Get(headers.ContentRange) != "" ||
+		!cw.compressibleContentType() {
+		return cw.passthrough()
+	}
+
+	enc, err := newEncoder(cw.encoding, cw.rw)
+	if err != nil {
+		return cw.passthrough()
+	}
+	cw.enc = enc
+	cw.state = compressEncoding
+
+	header.Set(headers.ContentEncoding, cw.encoding)
+	header.Del(headers.ContentLength)
+	cw.setEtag()
+	cw.rw.WriteHeader(cw.status)
+
+	_, err = cw.enc.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) passthrough() error {
+	cw.state = compressPassthrough
+	cw.rw.WriteHeader(cw.status)
+	_, err := cw.rw.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) finish() {
+	switch cw.state {
+	case compressEncoding:
+		releaseEncoder(cw.encoding, cw.enc)
+		cw.enc = nil
+	case compressBuffering:
+		// nothing was written
+		if cw.status == 0 {
+			return
+		}
+		cw.passthrough()
+	}
+}
+
+func (cw *compressWriter) setEtag() {
+	if etag := cw.Header().Get(headers.ETag); etag != "" {
+		cw.Header().Set(headers.ETag, encodedEtag(etag, cw.encoding))
+	}
+}
+
+func (cw *compressWriter) compressibleContentType() bool {
+	contentType := cw.Header().Get(headers.ContentType)
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+	}
+	return matchMediaType(contentType, cw.config.contentTypes)
+}
+
+// encodedEtag returns the etag of the representation encoded with encoding.
+func encodedEtag(etag, encoding string) string {
+	if strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, "\"") {
+		return etag
+	}
+	return strings.TrimSuffix(etag, "\"") + "-" + encoding + "\""
+}
+
+// stripEncodedEtags removes the encoding suffixes added by encodedEtag from a list of etags.
+func stripEncodedEtags(header string, encodings []string) string {
+	tags := strings.Split(header, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for _, encoding := range encodings {
+			if stripped, ok := strings.CutSuffix(tag, "-"+encoding+"\""); ok && !strings.HasPrefix(tag, "W/") {
+				tag = stripped + "\""
+				break
+			}
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ", ")
+}
+
+// negotiateEncoding returns the supported encoding with the highest q-value in an Accept-Encoding header,
+// ties are resolved with the order of supported. It returns an empty string for identity.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// matchMediaType reports whether the media type of contentType is in types.
+// A type ending with /* matches all its subtypes.
+func matchMediaType(contentType string, types []string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(types, func(candidate string) bool {
+		if prefix, ok := strings.CutSuffix(candidate, "/*"); ok {
+			return strings.HasPrefix(t, prefix+"/")
+		}
+		return t == candidate
+	})
+}
+
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values(headers.Vary) {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+	header.Add(headers.Vary, value)
+}
@@ -0,0 +1,130 @@
+package ginserver_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/andybalholm/brotli"
+	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionHandler(t *testing.T) {
+	body := strings.Repeat(`{"hello":"world"}`, 100)
+	handler := ginserver.CompressionHandler(ginserver.EtagHandler(
+		http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set(headers.ContentType, "application/json")
+			if req.Method == http.MethodPut {
+				// the conditional as seen by CheckPreconditions
+				res.Write([]byte(req.Header.Get(headers.IfMatch)))
+				return
+			}
+			if req.URL.Path == "/small" {
+				res.Write([]byte(`{}`))
+				return
+			}
+			res.Write([]byte(body))
+		})),
+		ginserver.WithCompressionEncodings(ginserver.EncodingGzip),
+	)
+
+	doMethod := func(method, path string, h map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	do := func(path string, h map[string]string) *httptest.ResponseRecorder {
+		return doMethod(http.MethodGet, path, h)
+	}
+
+	identity := do("/", nil)
+	require.Empty(t, identity.Header().Get(headers.ContentEncoding))
+	require.Equal(t, headers.AcceptEncoding, identity.Header().Get(headers.Vary))
+	require.Equal(t, body, identity.Body.String())
+
+	encoded := do("/", map[string]string{headers.AcceptEncoding: "br;q=1, gzip;q=0.5"})
+	t.Run("gzip", func(t *testing.T) {
+		require.Equal(t, ginserver.EncodingGzip, encoded.Header().Get(headers.ContentEncoding))
+		require.Equal(t, headers.AcceptEncoding, encoded.Header().Get(headers.Vary))
+
+		r, err := gzip.NewReader(encoded.Body)
+		require.NoError(t, err)
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, body, string(b))
+	})
+
+	t.Run("etags", func(t *testing.T) {
+		identityEtag := identity.Header().Get(headers.ETag)
+		encodedEtag := encoded.Header().Get(headers.ETag)
+		require.NotEmpty(t, identityEtag)
+		require.Equal(t, strings.TrimSuffix(identityEtag, `"`)+`-gzip"`, encodedEtag)
+
+		w := do("/", map[string]string{headers.AcceptEncoding: "gzip", headers.IfNoneMatch: encodedEtag})
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Equal(t, encodedEtag, w.Header().Get(headers.ETag))
+
+		w = do("/", map[string]string{headers.IfNoneMatch: identityEtag})
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Equal(t, identityEtag, w.Header().Get(headers.ETag))
+
+		// conditionals are stripped whatever the encoding of the response
+		w = doMethod(http.MethodHead, "/", map[string]string{headers.AcceptEncoding: "gzip", headers.IfNoneMatch: encodedEtag})
+		require.Equal(t, http.StatusNotModified, w.Code)
+		w = doMethod(http.MethodPut, "/", map[string]string{headers.IfMatch: encodedEtag})
+		require.Equal(t, identityEtag, w.Body.String())
+	})
+
+	t.Run("small responses are not compressed", func(t *testing.T) {
+		w := do("/small", map[string]string{headers.AcceptEncoding: "gzip"})
+		require.Empty(t, w.Header().Get(headers.ContentEncoding))
+		require.Equal(t, `{}`, w.Body.String())
+	})
+
+	t.Run("rejected encodings", func(t *testing.T) {
+		w := do("/", map[string]string{headers.AcceptEncoding: "gzip;q=0"})
+		require.Empty(t, w.Header().Get(headers.ContentEncoding))
+	})
+}
+
+func TestCompressionEncodersAreReused(t *testing.T) {
+	body := strings.Repeat(`{"hello":"world"}`, 100)
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		ginserver.EncodingGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		ginserver.EncodingBrotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		ginserver.EncodingZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for encoding, decode := range decoders {
+		handler := ginserver.CompressionHandler(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
+			res.Header().Set(headers.ContentType, "application/json")
+			res.Write([]byte(body))
+		}), ginserver.WithCompressionEncodings(encoding))
+
+		for range 3 {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(headers.AcceptEncoding, encoding)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(t, encoding, w.Header().Get(headers.ContentEncoding))
+
+			r, err := decode(w.Body)
+			require.NoError(t, err)
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, body, string(b), encoding)
+		}
+	}
+}
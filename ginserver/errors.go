@@ -1,42 +1,32 @@
 package ginserver
 
 import (
-	"errors"
-
 	"github.com/Lysoul/gocommon/shared"
 	"github.com/gin-gonic/gin"
 )
 
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = shared.ProblemContentType
+
+// Problem is an RFC 9457 problem details document.
+type Problem = shared.Problem
+
 // a simple handler for common errors, use as a fallback error handler.
+// errors are written as RFC 9457 problem details, see shared.HTTPErrors.
 func ErrorMiddleware() gin.HandlerFunc {
+	return ProblemMiddleware(shared.HTTPErrors())
+}
+
+// ProblemMiddleware writes the last error of the request as problem details using registry.
+func ProblemMiddleware(registry *shared.ErrorRegistry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
 		err := c.Errors.Last()
-		if err == nil {
+		if err == nil || c.Writer.Written() {
 			return
 		}
 
-		status, data, headers := errToHTTP(err.Err)
-		for k, v := range headers {
-			c.Header(k, v)
-		}
-
-		c.JSON(status, data)
-	}
-}
-
-func errToHTTP(err error) (int, any, map[string]string) {
-	switch {
-	case errors.Is(err, shared.ErrUnauthorized):
-		return 401, nil, nil
-	case errors.Is(err, shared.ErrPermissionDenied):
-		return 403, nil, nil
-	case errors.Is(err, shared.ErrNotFound):
-		return 404, nil, nil
-	case errors.Is(err, shared.ErrValidationFailed):
-		return 400, err, nil
-	default:
-		return 500, nil, nil
+		registry.Write(c, err.Err)
 	}
 }
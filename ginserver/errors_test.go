@@ -0,0 +1,184 @@
+package ginserver_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	errTeapot := errors.New("teapot")
+
+	registry := shared.NewErrorRegistry()
+	registry.Register(shared.ErrNotFound, shared.ErrorMapping{
+		Status:        http.StatusNotFound,
+		Code:          "not_found",
+		ExposeDetails: true,
+	})
+	registry.Register(errTeapot, shared.ErrorMapping{
+		Status: http.StatusTeapot,
+		Code:   "teapot",
+		Type:   "https://example.com/problems/teapot",
+	})
+
+	router := gin.New()
+	router.Use(ginserver.RequestID(), ginserver.ProblemMiddleware(registry))
+	router.GET("/not-found", func(c *gin.Context) {
+		c.Error(shared.NewError(shared.ErrNotFound, "", "user 1"))
+	})
+	router.GET("/no-rows", func(c *gin.Context) {
+		c.Error(shared.ErrNotFound.Wrap(fmt.Errorf("select users: %w", sql.ErrNoRows)))
+	})
+	router.GET("/teapot", func(c *gin.Context) {
+		c.Error(errTeapot)
+	})
+	router.GET("/unknown", func(c *gin.Context) {
+		c.Error(errors.New("db is down"))
+	})
+	router.GET("/unknown-code", func(c *gin.Context) {
+		c.Error(shared.NewError(shared.ConstError("ledger"), "ledger_locked", "ledger is locked"))
+	})
+
+	do := func(path string) (*httptest.ResponseRecorder, ginserver.Problem) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		p := ginserver.Problem{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		return w, p
+	}
+
+	t.Run("registered status", func(t *testing.T) {
+		w, p := do("/not-found")
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, ginserver.ProblemContentType, w.Header().Get("Content-Type"))
+		require.Equal(t, ginserver.Problem{
+			Type:      "about:blank",
+			Title:     "Not Found",
+			Status:    http.StatusNotFound,
+			Detail:    "user 1",
+			Instance:  "/not-found",
+			Code:      "not_found",
+			RequestID: "req-1",
+		}, p)
+	})
+
+	t.Run("wrapped causes are not exposed", func(t *testing.T) {
+		w, p := do("/no-rows")
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, "not_found", p.Detail)
+	})
+
+	t.Run("custom error", func(t *testing.T) {
+		w, p := do("/teapot")
+		require.Equal(t, http.StatusTeapot, w.Code)
+		require.Equal(t, "https://example.com/problems/teapot", p.Type)
+		require.Equal(t, "/teapot", p.Instance)
+		require.Empty(t, p.Detail)
+	})
+
+	t.Run("unknown errors are not exposed", func(t *testing.T) {
+		w, p := do("/unknown")
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Empty(t, p.Detail)
+		require.Equal(t, "unexpected", p.Code)
+
+		_, p = do("/unknown-code")
+		require.Equal(t, "unexpected", p.Code, "codes of unmapped errors are internal")
+	})
+}
+
+func TestErrorToHTTPMatchesErrorMiddleware(t *testing.T) {
+	type input struct {
+		Name string `validate:"required"`
+	}
+	validationErr := shared.Validator().Struct(input{})
+
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware())
+	router.GET("/middleware", func(c *gin.Context) {
+		c.Error(validationErr)
+	})
+	router.GET("/shared", func(c *gin.Context) {
+		shared.ErrorToHTTP(c, validationErr)
+	})
+
+	bodies := []string{}
+	for _, path := range []string{"/middleware", "/shared"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		p := ginserver.Problem{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		require.Equal(t, "validation_failed", p.Code)
+		require.NotEmpty(t, p.Errors)
+		p.Instance = ""
+		b, err := json.Marshal(p)
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+	}
+	require.Equal(t, bodies[0], bodies[1])
+	require.Contains(t, bodies[0],
+		`"errors":[{"field":"name","jsonPointer":"/name","message":"is required","rule":"required"}]`)
+}
+
+func TestErrorToHTTPStatuses(t *testing.T) {
+	router := gin.New()
+	router.GET("/:kind", func(c *gin.Context) {
+		shared.ErrorToHTTP(c, shared.ConstError(c.Param("kind")))
+	})
+
+	for kind, status := range map[string]int{
+		"unauthorized":      http.StatusUnauthorized,
+		"permission_denied": http.StatusForbidden,
+		"not_found":         http.StatusNotFound,
+		"validation_failed": http.StatusBadRequest,
+		"teapot":            http.StatusInternalServerError,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+kind, nil))
+		require.Equal(t, status, w.Code, kind)
+	}
+}
+
+func TestValidationErrorsLanguage(t *testing.T) {
+	type input struct {
+		Zip string `json:"zip_code" validate:"max=5"`
+	}
+
+	router := gin.New()
+	router.Use(ginserver.LanguageMiddleware([]string{"en", "th"}), ginserver.ErrorMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.Error(shared.Validator().Struct(input{Zip: "123456"}))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "th")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var body struct {
+		Errors []shared.ValidationDetail `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Equal(t, []shared.ValidationDetail{{
+		Field:       "zip_code",
+		JSONPointer: "/zip_code",
+		Rule:        "max",
+		Param:       "5",
+		Message:     "ต้องมีความยาวไม่เกิน 5 ตัวอักษร",
+	}}, body.Errors)
+}
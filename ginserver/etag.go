@@ -1,14 +1,22 @@
 package ginserver
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
+	"hash/fnv"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/go-http-utils/fresh"
 	"github.com/go-http-utils/headers"
 )
@@ -16,26 +24,140 @@ import (
 // Version is this package's version.
 const Version = "0.2.1"
 
+// EtagHash creates the hash used to compute etags.
+type EtagHash func() hash.Hash
+
+//nolint:gochecknoglobals // constant hash constructors.
+var (
+	EtagHashSHA1   EtagHash = sha1.New
+	EtagHashSHA256 EtagHash = sha256.New
+	EtagHashFNV    EtagHash = func() hash.Hash { return fnv.New64a() }
+	EtagHashXXHash EtagHash = func() hash.Hash { return xxhash.New() }
+)
+
+// EtagHashByName returns the hash named sha1, sha256, fnv or xxhash.
+func EtagHashByName(name string) (EtagHash, error) {
+	switch strings.ToLower(name) {
+	case "", "sha1":
+		return EtagHashSHA1, nil
+	case "sha256":
+		return EtagHashSHA256, nil
+	case "fnv":
+		return EtagHashFNV, nil
+	case "xxhash":
+		return EtagHashXXHash, nil
+	default:
+		return nil, fmt.Errorf("unknown etag hash %q", name)
+	}
+}
+
+type etagConfig struct {
+	weak                 bool
+	hash                 EtagHash
+	maxBufferSize        int
+	excludedContentTypes []string
+	excludedPaths        []string
+}
+
+// EtagOption configures EtagHandler.
+type EtagOption func(*etagConfig)
+
+// WithWeakEtag generates weak etags (W/"...").
+func WithWeakEtag(weak bool) EtagOption {
+	return func(c *etagConfig) {
+		c.weak = weak
+	}
+}
+
+// WithEtagHash sets the hash used to compute etags, defaults to EtagHashSHA1.
+func WithEtagHash(h EtagHash) EtagOption {
+	return func(c *etagConfig) {
+		c.hash = h
+	}
+}
+
+// WithEtagMaxBufferSize sets the maximum size of a response that is buffered to compute its etag.
+// Bigger responses are streamed to the client without etag. Zero means no limit.
+func WithEtagMaxBufferSize(size int) EtagOption {
+	return func(c *etagConfig) {
+		c.maxBufferSize = size
+	}
+}
+
+// WithEtagExcludedContentTypes skips responses with these media types, e.g. text/event-stream.
+// A type ending with /* matches all its subtypes, e.g. image/*.
+func WithEtagExcludedContentTypes(types ...string) EtagOption {
+	return func(c *etagConfig) {
+		c.excludedContentTypes = append(c.excludedContentTypes, types...)
+	}
+}
+
+// WithEtagExcludedPaths skips requests whose path starts with one of prefixes.
+func WithEtagExcludedPaths(prefixes ...string) EtagOption {
+	return func(c *etagConfig) {
+		c.excludedPaths = append(c.excludedPaths, prefixes...)
+	}
+}
+
+// FormatEtag formats an etag from the length and the hash of a representation.
+func FormatEtag(length int, sum []byte, weak bool) string {
+	etag := fmt.Sprintf("\"%v-%v\"", strconv.Itoa(length), hex.EncodeToString(sum))
+	if weak {
+		etag = "W/" + etag
+	}
+	return etag
+}
+
+// hashWriter buffers 200 responses to compute their etag.
+// Anything else (or anything too big, or flushed) is passed through to rw.
 type hashWriter struct {
 	rw     http.ResponseWriter
+	config *etagConfig
 	hash   hash.Hash
 	buf    *bytes.Buffer
 	len    int
 	status int
+
+	passthrough bool
 }
 
-func (hw hashWriter) Header() http.Header {
+func (hw *hashWriter) Header() http.Header {
 	return hw.rw.Header()
 }
 
 func (hw *hashWriter) WriteHeader(status int) {
+	// informational responses are sent as is
+	if status < http.StatusOK {
+		hw.rw.WriteHeader(status)
+		return
+	}
+	if hw.status != 0 {
+		return
+	}
 	hw.status = status
+
+	if status != http.StatusOK ||
+		hw.Header().Get(headers.ETag) != "" ||
+		hw.excludedContentType() {
+		hw.passthrough = true
+		hw.rw.WriteHeader(status)
+	}
 }
 
 func (hw *hashWriter) Write(b []byte) (int, error) {
 	if hw.status == 0 {
-		hw.status = http.StatusOK
+		hw.WriteHeader(http.StatusOK)
+	}
+	if hw.passthrough {
+		return hw.rw.Write(b)
+	}
+	if hw.config.maxBufferSize > 0 && hw.buf.Len()+len(b) > hw.config.maxBufferSize {
+		if err := hw.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return hw.rw.Write(b)
 	}
+
 	// bytes.Buffer.Write(b) always return (len(b), nil), so just
 	// ignore the return values.
 	hw.buf.Write(b)
@@ -45,44 +167,90 @@ func (hw *hashWriter) Write(b []byte) (int, error) {
 	return l, err
 }
 
-func writeRaw(res http.ResponseWriter, hw hashWriter) {
+// Flush sends the buffered response, etags can't be generated for streamed responses.
+func (hw *hashWriter) Flush() {
+	if !hw.passthrough {
+		if hw.status == 0 {
+			hw.WriteHeader(http.StatusOK)
+		}
+		if err := hw.startPassthrough(); err != nil {
+			return
+		}
+	}
+	if f, ok := hw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (hw *hashWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := hw.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("etag: response writer does not implement http.Hijacker")
+	}
+	hw.passthrough = true
+	return h.Hijack()
+}
+
+// Unwrap is used by http.ResponseController.
+func (hw *hashWriter) Unwrap() http.ResponseWriter {
+	return hw.rw
+}
+
+func (hw *hashWriter) startPassthrough() error {
+	hw.passthrough = true
+	hw.rw.WriteHeader(hw.status)
+	_, err := hw.rw.Write(hw.buf.Bytes())
+	hw.buf.Reset()
+	return err
+}
+
+func (hw *hashWriter) excludedContentType() bool {
+	return len(hw.config.excludedContentTypes) > 0 &&
+		matchMediaType(hw.Header().Get(headers.ContentType), hw.config.excludedContentTypes)
+}
+
+func writeRaw(res http.ResponseWriter, hw *hashWriter) {
 	res.WriteHeader(hw.status)
 	res.Write(hw.buf.Bytes())
 }
 
 // Handler wraps the http.Handler h with ETag support.
-func EtagHandler(h http.Handler) http.Handler {
-	weak := false
+// Only 200 responses to GET and HEAD requests get an etag.
+func EtagHandler(h http.Handler, opts ...EtagOption) http.Handler {
+	config := &etagConfig{hash: EtagHashSHA1}
+	for _, opt := range opts {
+		opt(config)
+	}
+
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if (req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+			slices.ContainsFunc(config.excludedPaths, func(prefix string) bool {
+				return strings.HasPrefix(req.URL.Path, prefix)
+			}) {
 			h.ServeHTTP(res, req)
 			return
 		}
 
-		hw := hashWriter{rw: res, hash: sha1.New(), buf: bytes.NewBuffer(nil)}
-		h.ServeHTTP(&hw, req)
-
-		resHeader := res.Header()
+		hw := &hashWriter{rw: res, config: config, hash: config.hash(), buf: bytes.NewBuffer(nil)}
+		h.ServeHTTP(hw, req)
 
-		if hw.hash == nil ||
-			resHeader.Get(headers.ETag) != "" ||
-			hw.status != 200 ||
-			// hw.status == http.StatusNoContent ||
-			hw.buf.Len() == 0 {
-			writeRaw(res, hw)
+		// already sent or nothing was written
+		if hw.passthrough || hw.status == 0 {
 			return
 		}
 
-		etag := fmt.Sprintf("\"%v-%v\"", strconv.Itoa(hw.len),
-			hex.EncodeToString(hw.hash.Sum(nil)))
+		resHeader := res.Header()
 
-		if weak {
-			etag = "W/" + etag
+		// HEAD handlers may not write a body, there is nothing to hash then
+		if hw.buf.Len() == 0 {
+			writeRaw(res, hw)
+			return
 		}
 
-		resHeader.Set(headers.ETag, etag)
+		resHeader.Set(headers.ETag, FormatEtag(hw.len, hw.hash.Sum(nil), config.weak))
 
 		if fresh.IsFresh(req.Header, resHeader) {
+			resHeader.Del(headers.ContentLength)
 			res.WriteHeader(http.StatusNotModified)
 			res.Write(nil)
 		} else {
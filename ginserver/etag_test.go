@@ -8,10 +8,12 @@ import (
 	"fmt"
 
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 
 	"github.com/Lysoul/gocommon/ginserver"
 	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -96,3 +98,70 @@ func handlerFunc(res http.ResponseWriter, req *http.Request) {
 
 	res.Write(testStrBytes)
 }
+
+func TestEtagOptions(t *testing.T) {
+	serve := func(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	t.Run("weak", func(t *testing.T) {
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(handlerFunc), ginserver.WithWeakEtag(true)), get)
+		require.Equal(t, "W/"+testStrEtag, w.Header().Get(headers.ETag))
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		h := sha256.Sum256(testStrBytes)
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(handlerFunc),
+			ginserver.WithEtagHash(ginserver.EtagHashSHA256)), get)
+		require.Equal(t, ginserver.FormatEtag(len(testStrBytes), h[:], false), w.Header().Get(headers.ETag))
+	})
+
+	t.Run("max buffer size", func(t *testing.T) {
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(handlerFunc),
+			ginserver.WithEtagMaxBufferSize(5)), get)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, w.Header().Get(headers.ETag))
+		require.Equal(t, testStrBytes, w.Body.Bytes())
+	})
+
+	t.Run("excluded content type", func(t *testing.T) {
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set(headers.ContentType, "image/png")
+			res.Write(testStrBytes)
+		}), ginserver.WithEtagExcludedContentTypes("image/*")), get)
+		require.Empty(t, w.Header().Get(headers.ETag))
+		require.Equal(t, testStrBytes, w.Body.Bytes())
+	})
+
+	t.Run("excluded path", func(t *testing.T) {
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(handlerFunc),
+			ginserver.WithEtagExcludedPaths("/files")), httptest.NewRequest(http.MethodGet, "/files/a", nil))
+		require.Empty(t, w.Header().Get(headers.ETag))
+	})
+
+	t.Run("head", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, "/", nil)
+		req.Header.Set(headers.IfNoneMatch, testStrEtag)
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(handlerFunc)), req)
+		require.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		flushed := false
+		w := serve(ginserver.EtagHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Write([]byte("data: 1\n\n"))
+			flusher, ok := res.(http.Flusher)
+			require.True(t, ok)
+			flusher.Flush()
+			flushed = true
+			res.Write([]byte("data: 2\n\n"))
+		})), get)
+		require.True(t, flushed)
+		require.True(t, w.Flushed)
+		require.Empty(t, w.Header().Get(headers.ETag))
+		require.Equal(t, "data: 1\n\ndata: 2\n\n", w.Body.String())
+	})
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/Lysoul/gocommon/ginserver/ratelimit"
+	"github.com/Lysoul/gocommon/shared"
 	"github.com/uptrace/opentelemetry-go-extra/otelzap"
 	"go.opentelemetry.io/otel/trace"
 
@@ -14,26 +18,50 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-
-	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 const (
 	xRequestIDKey = "X-Request-ID"
 )
 
-// we could have a stuct with these things attached to it
-// but whats the point...
-//
-//nolint:gochecknoglobals // we want to have a global config.
-var config Config
-
 type Config struct {
 	Mode    string   `envconfig:"HTTP_MODE" default:"debug"`
 	LogSkip []string `envconfig:"HTTP_LOG_SKIP" default:"/health,/metrics"`
 	Port    int      `envconfig:"HTTP_PORT" default:"3000"`
 	Prefix  string   `envconfig:"HTTP_PATH_PREFIX" default:""`
 
+	// listen address URL, tcp://:3000 or unix:///run/app.sock, defaults to :Port
+	ListenAddr string `envconfig:"HTTP_LISTEN_ADDR"`
+	// permissions of the unix socket file, zero keeps the umask default
+	SocketMode os.FileMode `envconfig:"HTTP_SOCKET_MODE" default:"0660"`
+	// serve HTTP/2 without TLS (h2c), e.g. behind a service mesh sidecar
+	H2C bool `envconfig:"HTTP_H2C" default:"false"`
+
+	// see http.Server, zero means no timeout
+	ReadTimeout       time.Duration `envconfig:"HTTP_READ_TIMEOUT" default:"0"`
+	ReadHeaderTimeout time.Duration `envconfig:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	WriteTimeout      time.Duration `envconfig:"HTTP_WRITE_TIMEOUT" default:"0"`
+	IdleTimeout       time.Duration `envconfig:"HTTP_IDLE_TIMEOUT" default:"0"`
+	// zero means http.DefaultMaxHeaderBytes
+	MaxHeaderBytes int `envconfig:"HTTP_MAX_HEADER_BYTES" default:"0"`
+
+	// serve HTTPS when both are set, the files are reloaded when they change
+	TLSCertFile string `envconfig:"HTTP_TLS_CERT_FILE"`
+	TLSKeyFile  string `envconfig:"HTTP_TLS_KEY_FILE"`
+	// CA bundle to verify client certificates with, required by verify-if-given and require-and-verify
+	TLSClientCAFile string `envconfig:"HTTP_TLS_CLIENT_CA_FILE"`
+	// none, request, require, verify-if-given or require-and-verify
+	TLSClientAuth     string        `envconfig:"HTTP_TLS_CLIENT_AUTH" default:"none"`
+	TLSReloadInterval time.Duration `envconfig:"HTTP_TLS_RELOAD_INTERVAL" default:"1m"`
+
+	// on SIGTERM the health check reports not ready during DrainPeriod
+	// so load balancers stop sending traffic, then the server shuts down
+	// and waits ShutdownTimeout for in flight requests.
+	DrainPeriod     time.Duration `envconfig:"HTTP_DRAIN_PERIOD" default:"0"`
+	ShutdownTimeout time.Duration `envconfig:"HTTP_SHUTDOWN_TIMEOUT" default:"30s"`
+	// path of the health check on the router, leave empty to not register it
+	HealthPath string `envconfig:"HTTP_HEALTH_PATH"`
+
 	EnableCORS          bool          `envconfig:"HTTP_ENABLE_CORS" default:"false"`
 	AllowedOrigins      []string      `envconfig:"HTTP_ALLOWED_ORIGINS" default:"*"`
 	AllowedHeaders      []string      `envconfig:"HTTP_ALLOWED_HEADERS" default:"*"`
@@ -42,6 +70,59 @@ type Config struct {
 
 	// generate etag for all responses
 	EnableEtag bool `envconfig:"HTTP_ENABLE_ETAG" default:"false"`
+	// generate weak etags (W/"..."). If-Match never matches weak etags (strong comparison),
+	// clients then send If-Unmodified-Since: it can't be combined with RequirePreconditions
+	EtagWeak bool `envconfig:"HTTP_ETAG_WEAK" default:"false"`
+	// hash used for etags: sha1, sha256, fnv or xxhash
+	EtagHash string `envconfig:"HTTP_ETAG_HASH" default:"sha1"`
+	// bigger responses are streamed without etag, 0 to buffer everything
+	EtagMaxBufferSize        int      `envconfig:"HTTP_ETAG_MAX_BUFFER_SIZE" default:"1048576"`
+	EtagExcludedContentTypes []string `envconfig:"HTTP_ETAG_EXCLUDED_CONTENT_TYPES" default:"text/event-stream"`
+	EtagExcludedPaths        []string `envconfig:"HTTP_ETAG_EXCLUDED_PATHS"`
+
+	// content encodings in order of preference (zstd, br, gzip), leave empty to disable compression
+	Compression        []string `envconfig:"HTTP_COMPRESSION"`
+	CompressionMinSize int      `envconfig:"HTTP_COMPRESSION_MIN_SIZE" default:"1024"`
+	//nolint:lll // envconfig default
+	CompressionContentTypes []string `envconfig:"HTTP_COMPRESSION_CONTENT_TYPES" default:"text/*,application/json,application/problem+json,application/javascript,application/xml,image/svg+xml"`
+
+	// requests allowed per period and client, e.g. 100/1m or 10/s, leave empty to disable rate limiting
+	RateLimit string `envconfig:"HTTP_RATE_LIMIT"`
+	// token bucket size, defaults to the requests of RateLimit
+	RateLimitBurst int `envconfig:"HTTP_RATE_LIMIT_BURST" default:"0"`
+	// token-bucket or sliding-window
+	RateLimitAlgorithm string `envconfig:"HTTP_RATE_LIMIT_ALGORITHM" default:"token-bucket"`
+	// what requests are counted by: ip or route, combined when several.
+	// The limiter runs before authentication, limit by subject or API key with ratelimit.Middleware
+	// and ratelimit.KeyBySubject or ratelimit.KeyByAPIKey on the authenticated routes.
+	RateLimitKey []string `envconfig:"HTTP_RATE_LIMIT_KEY" default:"ip"`
+	// limits of routes, e.g. POST /login=5/1m
+	RateLimitRoutes []string `envconfig:"HTTP_RATE_LIMIT_ROUTES"`
+
+	// supported languages, the first is the default, leave empty to not negotiate the language
+	// of requests, see LanguageMiddleware
+	Languages []string `envconfig:"HTTP_LANGUAGES"`
+
+	// max size of request bodies read by Bind in bytes, 0 for unlimited
+	MaxBodySize int64 `envconfig:"HTTP_MAX_BODY_SIZE" default:"1048576"`
+	// Bind rejects bodies with fields that are not in the request struct
+	DisallowUnknownFields bool `envconfig:"HTTP_DISALLOW_UNKNOWN_FIELDS" default:"false"`
+
+	// path of the OpenAPI document of the routes registered with Server.Routes, served as
+	// <path>.json and <path>.yaml, e.g. /openapi. Leave empty to not serve it.
+	OpenAPIPath string `envconfig:"HTTP_OPENAPI_PATH"`
+	// version of the API in the OpenAPI document
+	OpenAPIVersion string `envconfig:"HTTP_OPENAPI_VERSION" default:"1.0.0"`
+	// file of an OpenAPI 3.1 document (JSON or YAML) the requests are validated against.
+	// See WithOpenAPISpec for embedded documents.
+	OpenAPISpec string `envconfig:"HTTP_OPENAPI_SPEC"`
+	// validate the responses against the OpenAPI document too, they are buffered:
+	// enable it in development and tests
+	OpenAPIValidateResponses bool `envconfig:"HTTP_OPENAPI_VALIDATE_RESPONSES" default:"false"`
+
+	// respond 428 to PUT, PATCH and DELETE requests without If-Match or If-Unmodified-Since
+	// handlers check them with CheckPreconditions. Requires strong etags, see EtagWeak
+	RequirePreconditions bool `envconfig:"HTTP_REQUIRE_PRECONDITIONS" default:"false"`
 
 	// default value to put in cache control, leave empty to disable
 	// `private, no-store, no-cache` are good options
@@ -54,76 +135,76 @@ type Config struct {
 }
 
 // returns router and function to run server.
-func InitGin(_config Config, logger *otelzap.Logger) (*gin.Engine, func() (*http.Server, func(context.Context))) {
-	config = _config
-
-	if config.Mode == "production" {
-		gin.SetMode(gin.ReleaseMode)
-	} else {
-		gin.SetMode(gin.DebugMode)
-	}
-	router := gin.New()
-	router.ContextWithFallback = true
-
-	if config.EnableCORS {
-		logger.Info("CORS enabled")
-		router.Use(CORSMiddleware(config))
-	} else {
-		logger.Info("CORS disabled")
-	}
-
-	router.Use(RequestID())
-	router.Use(otelgin.Middleware(config.ServiceName))
-	router.Use(
-		GinZapSetup(logger, config),
-		// Logs all panic to error log
-		//   - stack means whether output the stack info.
-		ginzap.RecoveryWithZap(logger.Logger, true),
-	)
-
-	router.Use(MetricsMiddleware(
-		config.MetricsDurationBuckets,
-		config.MetricsSizeBuckets,
-	))
-
-	if config.CacheContolDefault != "" {
-		router.Use(CacheControlMiddleware(config.CacheContolDefault))
+// It is a thin wrapper around New, see Server, and panics when New fails.
+func InitGin(
+	config Config,
+	logger *otelzap.Logger,
+	opts ...Option,
+) (*gin.Engine, func() (*http.Server, func(context.Context), error)) {
+	s, err := New(config, append(opts, WithLogger(logger))...)
+	if err != nil {
+		panic(err)
 	}
+	servers.Store(s.Router(), s)
+	return s.Router(), s.Run
+}
 
-	return router, func() (*http.Server, func(context.Context)) {
-		return Run(router)
+// Run runs the server of a router created by InitGin, see Server.Run.
+// Use Server.Run for servers created by New.
+func Run(router *gin.Engine) (*http.Server, func(context.Context), error) {
+	s, ok := servers.Load(router)
+	if !ok {
+		return nil, nil, errors.New("ginserver: the router was not created by InitGin or its server was shut down")
 	}
+	return s.(*Server).Run()
 }
 
-// to do return servver AND shutdown.
-func Run(router *gin.Engine) (*http.Server, func(context.Context)) {
-	var handler http.Handler
-	if config.EnableEtag {
-		handler = EtagHandler(router)
-	} else {
-		handler = router
+func rateLimitMiddleware(config Config, store ratelimit.Store) (gin.HandlerFunc, error) {
+	limit, err := ratelimit.ParseLimit(config.RateLimit)
+	if err != nil {
+		return nil, err
 	}
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", config.Port),
-		Handler:           handler,
-		ReadHeaderTimeout: 10 * time.Second,
+	limit.Burst = config.RateLimitBurst
+	if limit.Algorithm, err = ratelimit.ParseAlgorithm(config.RateLimitAlgorithm); err != nil {
+		return nil, err
 	}
 
-	go func() {
-		err := server.ListenAndServe()
-		if err != nil && errors.Is(err, http.ErrServerClosed) {
-			zap.L().Info("ListenAndServe", zap.Error(err))
+	keys := make([]ratelimit.KeyFunc, len(config.RateLimitKey))
+	for i, name := range config.RateLimitKey {
+		if strings.EqualFold(name, "subject") || strings.EqualFold(name, "api-key") {
+			// there is no subject nor API key before authentication, requests would be counted by IP
+			return nil, fmt.Errorf("rate limit key %q requires authentication, see ratelimit.KeyBySubject and KeyByAPIKey", name)
 		}
-	}()
-
-	return server, func(ctx context.Context) {
-		err := server.Shutdown(ctx)
-		if err != nil {
-			zap.L().Error("Server forced to shutdown:", zap.Error(err))
-		} else {
-			zap.L().Info("HTTP server has shut down")
+		if keys[i], err = ratelimit.KeyByName(name); err != nil {
+			return nil, err
 		}
 	}
+	opts, err := ratelimit.ParseRoutes(config.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, ratelimit.WithStore(store))
+	if len(keys) > 0 {
+		opts = append(opts, ratelimit.WithKey(ratelimit.Keys(keys...)))
+	}
+	return ratelimit.Middleware(limit, opts...), nil
+}
+
+// EtagOptions returns the options of the EtagHandler of servers with config,
+// pass them to JSONEtag to declare versions matching the etags the server sends.
+func (config Config) EtagOptions() []EtagOption {
+	hash, err := EtagHashByName(config.EtagHash)
+	if err != nil {
+		zap.L().Error("invalid etag hash, using sha1", zap.Error(err))
+		hash = EtagHashSHA1
+	}
+	return []EtagOption{
+		WithWeakEtag(config.EtagWeak),
+		WithEtagHash(hash),
+		WithEtagMaxBufferSize(config.EtagMaxBufferSize),
+		WithEtagExcludedContentTypes(config.EtagExcludedContentTypes...),
+		WithEtagExcludedPaths(config.EtagExcludedPaths...),
+	}
 }
 
 func GinZapSetup(
@@ -142,6 +223,14 @@ func GinZapSetup(
 				if requestID := c.Request.Header.Get("X-Request-Id"); requestID != "" {
 					fields = append(fields, zap.String("request_id", requestID))
 				}
+				// log authenticated subject
+				if subject := c.GetString(shared.SubjectContextKey); subject != "" {
+					fields = append(fields, zap.String("subject", subject))
+				}
+				// log client certificate
+				if subject := ClientCertSubject(c); subject != "" {
+					fields = append(fields, zap.String("client_cert_subject", subject))
+				}
 				// log trace and span ID
 				if trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid() {
 					fields = append(fields,
@@ -0,0 +1,243 @@
+package ginserver
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// NoContent is the response of handlers responding 204 No Content, see Handle.
+type NoContent struct{}
+
+// StatusCoder is implemented by responses choosing their status code, see Handle.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type handleConfig struct {
+	status      int
+	bindOptions []BindOption
+}
+
+// HandleOption configures Handle.
+type HandleOption func(*handleConfig)
+
+// WithStatus sets the status code of successful responses.
+func WithStatus(status int) HandleOption {
+	return func(c *handleConfig) {
+		c.status = status
+	}
+}
+
+// WithBindOptions sets the options of decoding the request body, see Bind.
+func WithBindOptions(opts ...BindOption) HandleOption {
+	return func(c *handleConfig) {
+		c.bindOptions = append(c.bindOptions, opts...)
+	}
+}
+
+// Handle adapts fn to gin, keeping gin out of the service layer:
+//
+//   - the request is bound into a Req: the JSON body (see Bind), then only the fields tagged
+//     uri, form (query parameters) and header, which take precedence over the body.
+//     The complete Req is then validated, its binding tags and with Validate.
+//   - fn is called with the request context.
+//   - Resp is written as JSON with the status of WithStatus, StatusCoder (unless Resp is nil),
+//     204 for NoContent, 201 for POST requests and 200 otherwise.
+//   - errors are added to the context and written by ErrorMiddleware.
+//
+// For example:
+//
+//	router.POST("/users", ginserver.Handle(users.Create))
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) gin.HandlerFunc {
+	config := &handleConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	fields := requestFields(reflect.TypeFor[Req]())
+
+	return func(c *gin.Context) {
+		req, err := bindRequest[Req](c, fields, config.bindOptions)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		resp, err := fn(c.Request.Context(), req)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		status := config.status
+		if coder, ok := any(resp).(StatusCoder); ok && status == 0 && !isNil(resp) {
+			status = coder.StatusCode()
+		}
+		if _, ok := any(resp).(NoContent); ok {
+			if status == 0 {
+				status = http.StatusNoContent
+			}
+			c.Status(status)
+			return
+		}
+		if status == 0 {
+			status = http.StatusOK
+			if c.Request.Method == http.MethodPost {
+				status = http.StatusCreated
+			}
+		}
+		c.JSON(status, resp)
+	}
+}
+
+func bindRequest[Req any](c *gin.Context, fields map[string][][]int, opts []BindOption) (Req, error) {
+	var req Req
+	if hasBody(c.Request) {
+		if err := decodeJSON(c, &req, opts); err != nil {
+			return req, err
+		}
+	}
+	// sources are mapped without validation, the request is validated once complete
+	sources := map[string]map[string][]string{
+		"uri":    pathParams(c.Params),
+		"form":   c.Request.URL.Query(),
+		"header": headerValues(reflect.TypeFor[Req](), fields["header"], c.Request.Header),
+	}
+	for _, tag := range []string{"uri", "form", "header"} {
+		if len(fields[tag]) == 0 {
+			continue
+		}
+		err := bindFields(&req, fields[tag], func(ptr any) error {
+			return binding.MapFormWithTag(ptr, sources[tag], tag)
+		})
+		if err != nil {
+			return req, bindError(err)
+		}
+	}
+	if binding.Validator != nil {
+		// binding tags, validated by gin when binding
+		if err := binding.Validator.ValidateStruct(req); err != nil {
+			return req, bindError(err)
+		}
+	}
+	return req, Validate(req)
+}
+
+func pathParams(params gin.Params) map[string][]string {
+	values := make(map[string][]string, len(params))
+	for _, p := range params {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+	return values
+}
+
+// headerValues returns the values of the headers of the fields at paths of t by the name of their
+// header tag, which gin matches case insensitively.
+func headerValues(t reflect.Type, paths [][]int, header http.Header) map[string][]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	values := map[string][]string{}
+	for _, path := range paths {
+		name, _, _ := strings.Cut(t.FieldByIndex(path).Tag.Get("header"), ",")
+		if v := header.Values(name); len(v) > 0 {
+			values[name] = v
+		}
+	}
+	return values
+}
+
+// isNil reports whether v is a nil pointer, map, slice or interface.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch value := reflect.ValueOf(v); value.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
+
+// bindFields binds only the fields of req at the paths: gin also binds the untagged fields
+// by their name, which must not override the body (e.g. ?Role=admin).
+func bindFields[Req any](req *Req, fields [][]int, bind func(any) error) error {
+	params := reflect.New(reflect.TypeFor[Req]())
+	dst, src := structValue(reflect.ValueOf(req).Elem()), structValue(params.Elem())
+	// fields missing from the request keep the values of the body
+	for _, field := range fields {
+		src.FieldByIndex(field).Set(dst.FieldByIndex(field))
+	}
+	if err := bind(params.Interface()); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		dst.FieldByIndex(field).Set(src.FieldByIndex(field))
+	}
+	return nil
+}
+
+// structValue dereferences v, allocating nil pointers.
+func structValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// requestFields returns the paths of the fields of t tagged uri, form and header, by tag,
+// in embedded and nested structs. Fields without them are only bound from the body.
+func requestFields(t reflect.Type) map[string][][]int {
+	fields := map[string][][]int{}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tagged := false
+		for _, tag := range []string{"uri", "form", "header"} {
+			if name, ok := field.Tag.Lookup(tag); ok {
+				tagged = true
+				if name != "-" {
+					fields[tag] = append(fields[tag], []int{i})
+				}
+			}
+		}
+		if tagged || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for tag, paths := range requestFields(field.Type) {
+			for _, path := range paths {
+				fields[tag] = append(fields[tag], append([]int{i}, path...))
+			}
+		}
+	}
+	return fields
+}
+
+// abortWithError aborts the request with the status of err,
+// ErrorMiddleware writes its problem details.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+	c.Status(shared.HTTPErrors().Lookup(err).Status)
+}
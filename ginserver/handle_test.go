@@ -0,0 +1,153 @@
+package ginserver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type updateOrder struct {
+	ID       int64  `json:"-" uri:"id" validate:"gt=0"`
+	Tenant   string `json:"-" header:"X-Tenant" validate:"required"`
+	DryRun   bool   `json:"-" form:"dry_run"`
+	Customer string `json:"customer" validate:"required"`
+}
+
+type order struct {
+	ID       int64  `json:"id"`
+	Tenant   string `json:"tenant"`
+	Customer string `json:"customer"`
+	DryRun   bool   `json:"dryRun"`
+}
+
+type accepted struct {
+	JobID string `json:"jobId"`
+}
+
+func (accepted) StatusCode() int {
+	return http.StatusAccepted
+}
+
+type renameOrder struct {
+	ID     int64  `json:"-" uri:"id" binding:"required"`
+	Tenant string `json:"-" header:"x-tenant" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+}
+
+type job struct {
+	ID string `json:"id"`
+}
+
+func (j *job) StatusCode() int {
+	if j.ID == "" {
+		return http.StatusOK
+	}
+	return http.StatusAccepted
+}
+
+type orderService struct{}
+
+func (orderService) Create(_ context.Context, req createOrder) (order, error) {
+	return order{ID: 1, Customer: req.Customer}, nil
+}
+
+func (orderService) Update(_ context.Context, req updateOrder) (order, error) {
+	if req.ID == 404 {
+		return order{}, shared.ErrNotFound
+	}
+	return order{ID: req.ID, Tenant: req.Tenant, Customer: req.Customer, DryRun: req.DryRun}, nil
+}
+
+func (orderService) Delete(_ context.Context, _ orderURI) (ginserver.NoContent, error) {
+	return ginserver.NoContent{}, nil
+}
+
+func (orderService) Rename(_ context.Context, req renameOrder) (*job, error) {
+	if req.Name == "unchanged" {
+		return nil, nil
+	}
+	return &job{ID: "job-1"}, nil
+}
+
+func (orderService) Export(_ context.Context, _ struct{}) (accepted, error) {
+	return accepted{JobID: "job-1"}, nil
+}
+
+func TestHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := orderService{}
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware())
+	router.POST("/orders", ginserver.Handle(service.Create))
+	router.PUT("/orders/:id", ginserver.Handle(service.Update))
+	router.PATCH("/orders/:id", ginserver.Handle(service.Update, ginserver.WithStatus(http.StatusAccepted)))
+	router.DELETE("/orders/:id", ginserver.Handle(service.Delete))
+	router.POST("/orders/export", ginserver.Handle(service.Export))
+	router.PUT("/orders/:id/name", ginserver.Handle(service.Rename))
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Tenant", "acme")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/orders", `{"customer":"c1","items":[{"sku":"a","quantity":1}]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.JSONEq(t, `{"id":1,"tenant":"","customer":"c1","dryRun":false}`, w.Body.String())
+
+	// path, query and header parameters take precedence over the body
+	w = do(http.MethodPut, "/orders/7?dry_run=true", `{"customer":"c1","id":9}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id":7,"tenant":"acme","customer":"c1","dryRun":true}`, w.Body.String())
+
+	// fields without uri, form or header tags are only bound from the body
+	req := httptest.NewRequest(http.MethodPut, "/orders/7?Customer=admin&Tenant=other", strings.NewReader(`{"customer":"c1"}`))
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("Customer", "admin")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id":7,"tenant":"acme","customer":"c1","dryRun":false}`, w.Body.String())
+
+	require.Equal(t, http.StatusAccepted, do(http.MethodPatch, "/orders/7", `{"customer":"c1"}`).Code)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/orders/7", "").Code)
+
+	w = do(http.MethodPost, "/orders/export", "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.JSONEq(t, `{"jobId":"job-1"}`, w.Body.String())
+
+	// binding tags are validated once all sources are bound
+	require.Equal(t, http.StatusAccepted, do(http.MethodPut, "/orders/7/name", `{"name":"n"}`).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/orders/7/name", `{}`).Code)
+	// nil responses do not call StatusCode
+	w = do(http.MethodPut, "/orders/7/name", `{"name":"unchanged"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "null", w.Body.String())
+
+	w = do(http.MethodPut, "/orders/0", `{}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `"jsonPointer":"/customer"`)
+
+	w = do(http.MethodPut, "/orders/404", `{"customer":"c1"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, shared.ProblemContentType, w.Header().Get("Content-Type"))
+
+	t.Run("without ErrorMiddleware", func(t *testing.T) {
+		router := gin.New()
+		router.PUT("/orders/:id", ginserver.Handle(service.Update))
+		req := httptest.NewRequest(http.MethodPut, "/orders/404", strings.NewReader(`{"customer":"c1"}`))
+		req.Header.Set("X-Tenant", "acme")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
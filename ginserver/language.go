@@ -0,0 +1,162 @@
+package ginserver
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+)
+
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage returns the language ranges of an Accept-Language header
+// by decreasing q-value, ranges with q=0 are left out.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			ranges = append(ranges, languageRange{tag: tag, q: q})
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b languageRange) int {
+		return cmp.Compare(b.q, a.q)
+	})
+	return ranges
+}
+
+// NegotiateLanguage returns the supported language best matching an Accept-Language header,
+// using the lookup scheme of RFC 4647 section 3.4: ranges are tried by decreasing q-value and
+// truncated until they match (en-US falls back to en). A range also matches a more specific
+// supported tag (en matches en-US), and * matches the first supported language.
+func NegotiateLanguage(acceptLanguage string, supported []string) (string, bool) {
+	for _, r := range parseAcceptLanguage(acceptLanguage) {
+		if r.tag == "*" {
+			if len(supported) > 0 {
+				return supported[0], true
+			}
+			continue
+		}
+		for tag := r.tag; tag != ""; tag = shared.TruncateLanguageTag(tag) {
+			if i := slices.IndexFunc(supported, func(s string) bool { return strings.EqualFold(s, tag) }); i >= 0 {
+				return supported[i], true
+			}
+		}
+		if i := slices.IndexFunc(supported, func(s string) bool {
+			return len(s) > len(r.tag) && s[len(r.tag)] == '-' && strings.EqualFold(s[:len(r.tag)], r.tag)
+		}); i >= 0 {
+			return supported[i], true
+		}
+	}
+	return "", false
+}
+
+// GetAcceptedLanguage returns the supported language best matching the Accept-Language header,
+// see NegotiateLanguage. It defaults to the first supported language, or an empty string
+// when there is none.
+func GetAcceptedLanguage(ctx *gin.Context, supported []string) string {
+	if lang, ok := NegotiateLanguage(ctx.GetHeader(headers.AcceptLanguage), supported); ok {
+		return lang
+	}
+	if len(supported) == 0 {
+		return ""
+	}
+	return supported[0]
+}
+
+type languageConfig struct {
+	query  string
+	cookie string
+}
+
+// LanguageOption configures LanguageMiddleware.
+type LanguageOption func(*languageConfig)
+
+// WithLanguageQuery sets the query parameter overriding Accept-Language, defaults to lang.
+// An empty name disables the override.
+func WithLanguageQuery(name string) LanguageOption {
+	return func(c *languageConfig) {
+		c.query = name
+	}
+}
+
+// WithLanguageCookie sets the cookie overriding Accept-Language, defaults to lang.
+// An empty name disables the override.
+func WithLanguageCookie(name string) LanguageOption {
+	return func(c *languageConfig) {
+		c.cookie = name
+	}
+}
+
+// LanguageMiddleware negotiates the language of requests among supported.
+// The lang query parameter, then the lang cookie, override Accept-Language when supported.
+// The language is stored in the gin context (GetLanguage) and the request context
+// (shared.LanguageFromContext), and sent in Content-Language with Vary: Accept-Language,
+// and Vary: Cookie when the cookie override is enabled.
+func LanguageMiddleware(supported []string, opts ...LanguageOption) gin.HandlerFunc {
+	config := &languageConfig{query: "lang", cookie: "lang"}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(c *gin.Context) {
+		var overrides []string
+		if config.query != "" {
+			overrides = append(overrides, c.Query(config.query))
+		}
+		if config.cookie != "" {
+			if cookie, err := c.Cookie(config.cookie); err == nil {
+				overrides = append(overrides, cookie)
+			}
+		}
+
+		lang := ""
+		for _, override := range overrides {
+			if override == "" {
+				continue
+			}
+			if l, ok := NegotiateLanguage(override, supported); ok {
+				lang = l
+				break
+			}
+		}
+		if lang == "" {
+			lang = GetAcceptedLanguage(c, supported)
+		}
+
+		addVary(c.Writer.Header(), headers.AcceptLanguage)
+		if config.cookie != "" {
+			addVary(c.Writer.Header(), headers.Cookie)
+		}
+		if lang != "" {
+			c.Header(headers.ContentLanguage, lang)
+			c.Set(shared.LanguageContextKey, lang)
+			c.Request = c.Request.WithContext(shared.WithLanguage(c.Request.Context(), lang))
+		}
+		c.Next()
+	}
+}
+
+// GetLanguage returns the language negotiated by LanguageMiddleware.
+func GetLanguage(c *gin.Context) string {
+	return c.GetString(shared.LanguageContextKey)
+}
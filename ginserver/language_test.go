@@ -0,0 +1,96 @@
+package ginserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	supported := []string{"en", "th", "zh-Hant", "pt-BR"}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"th", "th"},
+		{"th-TH,th;q=0.9,en;q=0.8", "th"},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", "en"},
+		{"en;q=0.5, th;q=0.8", "th"},
+		{"TH", "th"},
+		{"zh-Hant-TW", "zh-Hant"},
+		{"pt", "pt-BR"},
+		{"th;q=0, en", "en"},
+		{"fr, *;q=0.1", "en"},
+		{"fr", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := ginserver.NegotiateLanguage(tt.header, supported)
+			require.Equal(t, tt.want != "", ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetAcceptedLanguage(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set(headers.AcceptLanguage, "th-TH,th;q=0.9,en;q=0.8")
+
+	require.Equal(t, "th", ginserver.GetAcceptedLanguage(c, []string{"en", "th"}))
+	require.Equal(t, "en", ginserver.GetAcceptedLanguage(c, []string{"en", "fr"}))
+	require.NotPanics(t, func() {
+		require.Empty(t, ginserver.GetAcceptedLanguage(c, nil))
+	})
+}
+
+func TestLanguageMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginserver.LanguageMiddleware([]string{"en", "th"}))
+	router.GET("/", func(c *gin.Context) {
+		require.Equal(t, ginserver.GetLanguage(c), shared.LanguageFromContext(c.Request.Context()))
+		c.String(http.StatusOK, ginserver.GetLanguage(c))
+	})
+
+	get := func(target string, acceptLanguage string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(headers.AcceptLanguage, acceptLanguage)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/", "th-TH,en;q=0.5", nil)
+	require.Equal(t, "th", w.Body.String())
+	require.Equal(t, "th", w.Header().Get(headers.ContentLanguage))
+	require.Equal(t, []string{headers.AcceptLanguage, headers.Cookie}, w.Header().Values(headers.Vary))
+
+	require.Equal(t, "en", get("/", "fr", nil).Body.String())
+	require.Equal(t, "en", get("/?lang=en", "th", nil).Body.String())
+	require.Equal(t, "th", get("/", "en", &http.Cookie{Name: "lang", Value: "th"}).Body.String())
+	// the query parameter wins over the cookie
+	require.Equal(t, "en", get("/?lang=en-GB", "th", &http.Cookie{Name: "lang", Value: "th"}).Body.String())
+	// unsupported overrides are ignored
+	require.Equal(t, "th", get("/?lang=fr", "th", nil).Body.String())
+
+	router = gin.New()
+	router.Use(ginserver.LanguageMiddleware([]string{"en", "th"}, ginserver.WithLanguageCookie("")))
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, ginserver.GetLanguage(c))
+	})
+	w = get("/", "th", &http.Cookie{Name: "lang", Value: "en"})
+	require.Equal(t, "th", w.Body.String())
+	require.Equal(t, []string{headers.AcceptLanguage}, w.Header().Values(headers.Vary))
+}
@@ -0,0 +1,105 @@
+package ginserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ParseListenAddr parses a listen address URL, tcp://host:port or unix:///path/to.sock,
+// into the network and address of net.Listen. Addresses without scheme are tcp.
+func ParseListenAddr(addr string) (string, string, error) {
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		return "tcp", addr, nil
+	}
+	switch scheme {
+	case "tcp", "tcp4", "tcp6":
+		return scheme, rest, nil
+	case "unix":
+		u, err := url.Parse(addr)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+		}
+		// unix://relative.sock has no path, only a host
+		path := u.Host + u.Path
+		if path == "" {
+			return "", "", fmt.Errorf("invalid listen address %q: missing socket path", addr)
+		}
+		return scheme, path, nil
+	default:
+		return "", "", fmt.Errorf("invalid listen address %q: unsupported scheme %q", addr, scheme)
+	}
+}
+
+// listenAddr returns the network and address the server listens on.
+func (config Config) listenAddr() (string, string, error) {
+	if config.ListenAddr == "" {
+		return "tcp", fmt.Sprintf(":%d", config.Port), nil
+	}
+	return ParseListenAddr(config.ListenAddr)
+}
+
+// listen binds network and address. Stale unix socket files, left by a process
+// that did not shut down cleanly, are removed and the socket gets mode.
+// The socket file is removed when the listener is closed.
+func listen(network, address string, mode fs.FileMode) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	if err := removeStaleSocket(address); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(address, mode); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("chmod socket: %w", err)
+		}
+	}
+	return listener, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	// another process still serves on the socket
+	dialer := net.Dialer{Timeout: time.Second}
+	if conn, err := dialer.DialContext(context.Background(), "unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: address already in use", path)
+	}
+	return os.Remove(path)
+}
+
+// protocols returns the protocols served, HTTP/2 without TLS (h2c) is enabled by config.H2C.
+func (config Config) protocols() *http.Protocols {
+	if !config.H2C {
+		return nil
+	}
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
@@ -0,0 +1,109 @@
+package ginserver_test
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+func TestParseListenAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network string
+		address string
+	}{
+		{":3000", "tcp", ":3000"},
+		{"tcp://:3000", "tcp", ":3000"},
+		{"tcp6://[::1]:3000", "tcp6", "[::1]:3000"},
+		{"unix:///run/app.sock", "unix", "/run/app.sock"},
+		{"unix://app.sock", "unix", "app.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			network, address, err := ginserver.ParseListenAddr(tt.addr)
+			require.NoError(t, err)
+			require.Equal(t, tt.network, network)
+			require.Equal(t, tt.address, address)
+		})
+	}
+
+	for _, addr := range []string{"udp://:3000", "unix://"} {
+		_, _, err := ginserver.ParseListenAddr(addr)
+		require.Error(t, err, addr)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	// socket paths are limited to about 100 bytes, t.TempDir can be too long
+	dir, err := os.MkdirTemp("", "ginserver")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "app.sock")
+
+	// a socket left by a process that crashed
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	s, err := ginserver.New(ginserver.Config{
+		Mode:       "dev",
+		ListenAddr: "unix://" + path,
+		SocketMode: 0o600,
+		H2C:        true,
+	}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+	s.Router().GET("/proto", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Proto)
+	})
+	require.NoError(t, s.Start())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+
+	t.Run("in use", func(t *testing.T) {
+		other, err := ginserver.New(ginserver.Config{Mode: "dev", ListenAddr: "unix://" + path},
+			ginserver.WithLogger(otelzap.New(zap.NewNop())))
+		require.NoError(t, err)
+		require.ErrorContains(t, other.Start(), "address already in use")
+	})
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+	get := func(t *testing.T, transport *http.Transport) *http.Response {
+		t.Helper()
+		transport.DialContext = dial
+		res, err := (&http.Client{Transport: transport}).Get("http://app/proto")
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		return res
+	}
+
+	t.Run("HTTP/1.1", func(t *testing.T) {
+		require.Equal(t, 1, get(t, &http.Transport{}).ProtoMajor)
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		protocols := &http.Protocols{}
+		protocols.SetUnencryptedHTTP2(true)
+		require.Equal(t, 2, get(t, &http.Transport{Protocols: protocols}).ProtoMajor)
+	})
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -3,27 +3,45 @@ package ginserver
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	middlewaremetrics "github.com/slok/go-http-metrics/metrics"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
 	"github.com/slok/go-http-metrics/middleware"
 )
 
+// the prometheus recorder can only be registered once,
+// servers in the same process share it (and the buckets of the first one).
+//
+//nolint:gochecknoglobals // guarded by metricsRecorderMu.
+var (
+	metricsRecorderMu sync.Mutex
+	metricsRecorder   middlewaremetrics.Recorder
+)
+
 func MetricsMiddleware(
 	durationBuckets []float64,
 	sizeBuckets []float64,
 ) gin.HandlerFunc {
-	// Create our middleware.
-	mdlw := middleware.New(middleware.Config{
-		DisableMeasureInflight: true,
-		Recorder: metrics.NewRecorder(metrics.Config{
+	metricsRecorderMu.Lock()
+	if metricsRecorder == nil {
+		metricsRecorder = metrics.NewRecorder(metrics.Config{
 			StatusCodeLabel: "http_status_code",
 			HandlerIDLabel:  "http_route",
 			MethodLabel:     "http_method",
 			DurationBuckets: durationBuckets,
 			// this is useless
 			SizeBuckets: sizeBuckets,
-		}),
+		})
+	}
+	recorder := metricsRecorder
+	metricsRecorderMu.Unlock()
+
+	// Create our middleware.
+	mdlw := middleware.New(middleware.Config{
+		DisableMeasureInflight: true,
+		Recorder:               recorder,
 	})
 	return GinMetricsHandler("", mdlw)
 }
@@ -34,6 +34,10 @@ func CacheControlMiddleware(value string) gin.HandlerFunc {
 	}
 }
 
+// WithContentType responds 415 to requests without one of mimetypes as content type.
+//
+// Deprecated: use OpenAPIValidationMiddleware, which checks the content types of the
+// request bodies of the OpenAPI document.
 func WithContentType(mimetypes []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !HasContentType(c.Request, mimetypes) {
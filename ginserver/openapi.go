@@ -0,0 +1,206 @@
+package ginserver
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"path"
+	"reflect"
+	"sync"
+
+	"github.com/Lysoul/gocommon/ginserver/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// API collects the documentation of routes, served as an OpenAPI document.
+type API struct {
+	mu        sync.Mutex
+	info      openapi.Info
+	routes    []openapi.Route
+	generator func() *openapi.Generator
+}
+
+// NewAPI creates an API documented with info.
+func NewAPI(info openapi.Info) *API {
+	return &API{info: info, generator: openapi.NewGenerator}
+}
+
+// SetGenerator sets the schema generator of the document, e.g. to define the schemas
+// of types with a custom JSON encoding.
+func (a *API) SetGenerator(generator func() *openapi.Generator) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.generator = generator
+}
+
+// Add documents route.
+func (a *API) Add(route openapi.Route) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.routes = append(a.routes, route)
+}
+
+// Document returns the OpenAPI document of the routes.
+func (a *API) Document() *openapi.Document {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return openapi.Build(a.info, a.routes, a.generator())
+}
+
+// Handler serves the document as JSON, or as YAML when yaml is true.
+// The document is generated on the first request, after the routes are registered.
+func (a *API) Handler(yaml bool) gin.HandlerFunc {
+	document := sync.OnceValues(func() ([]byte, error) {
+		if yaml {
+			return a.Document().YAML()
+		}
+		return a.Document().JSON()
+	})
+	return func(c *gin.Context) {
+		b, err := document()
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		contentType := "application/json"
+		if yaml {
+			contentType = "application/yaml"
+		}
+		c.Data(http.StatusOK, contentType, b)
+	}
+}
+
+// Routes returns the routes of group documented in a.
+func (a *API) Routes(group *gin.RouterGroup) *Routes {
+	return &Routes{api: a, group: group}
+}
+
+// Routes registers routes on a gin router group and documents them.
+type Routes struct {
+	api   *API
+	group *gin.RouterGroup
+}
+
+// Group returns the routes of a sub group, see gin.RouterGroup.Group.
+func (r *Routes) Group(relativePath string, handlers ...gin.HandlerFunc) *Routes {
+	return &Routes{api: r.api, group: r.group.Group(relativePath, handlers...)}
+}
+
+// Handle registers handlers and documents the route with opts, use Request and Response
+// to document its types. See Register for typed handlers.
+func (r *Routes) Handle(method, relativePath string, opts []RouteOption, handlers ...gin.HandlerFunc) {
+	r.group.Handle(method, relativePath, handlers...)
+	r.api.Add(newRoute(method, path.Join(r.group.BasePath(), relativePath), opts))
+}
+
+// RouteOption documents a route.
+type RouteOption func(*openapi.Route)
+
+// Summary sets the summary of the route.
+func Summary(summary string) RouteOption {
+	return func(r *openapi.Route) {
+		r.Summary = summary
+	}
+}
+
+// Description sets the description of the route.
+func Description(description string) RouteOption {
+	return func(r *openapi.Route) {
+		r.Description = description
+	}
+}
+
+// Tags adds tags grouping the route.
+func Tags(tags ...string) RouteOption {
+	return func(r *openapi.Route) {
+		r.Tags = append(r.Tags, tags...)
+	}
+}
+
+// OperationID sets the ID of the operation, generated from the method and path by default.
+func OperationID(id string) RouteOption {
+	return func(r *openapi.Route) {
+		r.OperationID = id
+	}
+}
+
+// Deprecated marks the route deprecated.
+func Deprecated() RouteOption {
+	return func(r *openapi.Route) {
+		r.Deprecated = true
+	}
+}
+
+// Errors documents the errors the route may fail with, e.g. shared.ErrNotFound.
+func Errors(errs ...error) RouteOption {
+	return func(r *openapi.Route) {
+		r.Errors = append(r.Errors, errs...)
+	}
+}
+
+// Status sets the status of successful responses.
+func Status(status int) RouteOption {
+	return func(r *openapi.Route) {
+		r.Status = status
+	}
+}
+
+// Request documents the request of the route as bound from v, see openapi.Route.
+func Request(v any) RouteOption {
+	return func(r *openapi.Route) {
+		r.Request = reflect.TypeOf(v)
+	}
+}
+
+// Response documents the body of successful responses as v.
+func Response(v any) RouteOption {
+	return func(r *openapi.Route) {
+		r.Response = reflect.TypeOf(v)
+	}
+}
+
+func newRoute(method, fullPath string, opts []RouteOption) openapi.Route {
+	route := openapi.Route{Method: method, Path: fullPath}
+	for _, opt := range opts {
+		opt(&route)
+	}
+	return route
+}
+
+// Register registers fn as a typed handler (see Handle) and documents the route
+// with the request and response types of fn.
+//
+//	ginserver.Register(routes, http.MethodGet, "/users/:id", users.Get,
+//		ginserver.Summary("Get a user"), ginserver.Tags("users"), ginserver.Errors(shared.ErrNotFound))
+func Register[Req, Resp any](
+	r *Routes,
+	method, relativePath string,
+	fn func(ctx context.Context, req Req) (Resp, error),
+	opts ...RouteOption,
+) {
+	fullPath := path.Join(r.group.BasePath(), relativePath)
+	route := newRoute(method, fullPath, opts)
+	route.Request = reflect.TypeFor[Req]()
+
+	var handleOpts []HandleOption
+	if route.Status != 0 {
+		handleOpts = append(handleOpts, WithStatus(route.Status))
+	}
+	var resp Resp
+	coder, isCoder := any(resp).(StatusCoder)
+	switch {
+	case reflect.TypeFor[Resp]() == reflect.TypeFor[NoContent]():
+		route.Status = cmp.Or(route.Status, http.StatusNoContent)
+	case isCoder && reflect.TypeFor[Resp]().Kind() != reflect.Pointer:
+		route.Status = cmp.Or(route.Status, coder.StatusCode())
+		route.Response = reflect.TypeFor[Resp]()
+	case method == http.MethodPost:
+		route.Status = cmp.Or(route.Status, http.StatusCreated)
+		route.Response = reflect.TypeFor[Resp]()
+	default:
+		route.Response = reflect.TypeFor[Resp]()
+	}
+
+	r.group.Handle(method, relativePath, Handle(fn, handleOpts...))
+	r.api.Add(route)
+}
@@ -0,0 +1,247 @@
+package openapi
+
+import (
+	"cmp"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+)
+
+// Route describes an operation of the API.
+type Route struct {
+	// Method and Path of the route, a gin path (/users/:id).
+	Method string
+	Path   string
+
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+
+	// Request is bound from the fields tagged uri (path parameters), form (query parameters),
+	// header (header parameters), the other fields are the JSON body. Nil without request.
+	Request reflect.Type
+	// Response is the JSON body of successful responses, nil without content.
+	Response reflect.Type
+	// Status of successful responses, defaults to 200.
+	Status int
+	// Errors the operation may fail with, documented with the status and code of
+	// shared.HTTPErrors. Validation failures are documented for operations with a request.
+	Errors []error
+}
+
+// Build returns the document of routes, generating the schemas of their types with g
+// (NewGenerator when nil).
+func Build(info Info, routes []Route, g *Generator) *Document {
+	if g == nil {
+		g = NewGenerator()
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+	}
+
+	var tags []string
+	for _, route := range routes {
+		path := Path(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		if op := item.Operation(route.Method); op != nil {
+			*op = operation(g, route)
+		}
+		for _, tag := range route.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	for _, tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	if len(g.Schemas()) > 0 {
+		doc.Components = &Components{Schemas: g.Schemas()}
+	}
+	return doc
+}
+
+// Path converts a gin path to an OpenAPI path, /users/:id to /users/{id}.
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		} else if name, ok := strings.CutPrefix(segment, "*"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func operation(g *Generator, route Route) *Operation {
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Deprecated:  route.Deprecated,
+		Responses:   map[string]*Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(route.Method, route.Path)
+	}
+
+	errs := route.Errors
+	if route.Request != nil {
+		op.Parameters, op.RequestBody = request(g, route.Request)
+		if len(op.Parameters) > 0 || op.RequestBody != nil {
+			errs = append([]error{shared.ErrValidationFailed}, errs...)
+		}
+	}
+
+	status := cmp.Or(route.Status, http.StatusOK)
+	response := &Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		response.Content = map[string]*MediaType{
+			"application/json": {Schema: g.Schema(route.Response)},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	for _, err := range errs {
+		mapping := shared.HTTPErrors().Lookup(err)
+		key := strconv.Itoa(mapping.Status)
+		if response, ok := op.Responses[key]; ok {
+			if !strings.Contains(response.Description, mapping.Code) {
+				response.Description += ", " + mapping.Code
+			}
+			continue
+		}
+		op.Responses[key] = &Response{
+			Description: http.StatusText(mapping.Status) + ": " + mapping.Code,
+			Content: map[string]*MediaType{
+				shared.ProblemContentType: {Schema: problemSchema(g)},
+			},
+		}
+	}
+	return op
+}
+
+// request returns the parameters and the body of a request type.
+func request(g *Generator, t reflect.Type) ([]*Parameter, *RequestBody) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, jsonBody(g.Schema(t))
+	}
+
+	var params []*Parameter
+	bodyFields := 0
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				walk(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if param := parameter(g, field); param != nil {
+				params = append(params, param)
+				continue
+			}
+			if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "-" {
+				bodyFields++
+			}
+		}
+	}
+	walk(t)
+
+	if bodyFields == 0 {
+		return params, nil
+	}
+	if len(params) == 0 {
+		return nil, jsonBody(g.Schema(t))
+	}
+	return params, jsonBody(g.Struct(t, func(field reflect.StructField) bool {
+		in, _ := param(field)
+		return in != ""
+	}))
+}
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// param returns where and under which name the field is bound, empty for body fields.
+func param(field reflect.StructField) (string, string) {
+	for _, p := range []struct{ tag, in string }{{"uri", "path"}, {"form", "query"}, {"header", "header"}} {
+		if name, _, _ := strings.Cut(field.Tag.Get(p.tag), ","); name != "" && name != "-" {
+			return p.in, name
+		}
+	}
+	return "", ""
+}
+
+func parameter(g *Generator, field reflect.StructField) *Parameter {
+	in, name := param(field)
+	if in == "" {
+		return nil
+	}
+	schema, required := g.Field(field)
+	return &Parameter{
+		Name:     name,
+		In:       in,
+		Required: required || in == "path",
+		Schema:   schema,
+	}
+}
+
+// problemSchema returns the schema of shared.Problem,
+// with the validation errors as shared.ValidationDetail.
+func problemSchema(g *Generator) *Schema {
+	ref := g.Schema(reflect.TypeFor[shared.Problem]())
+	name := strings.TrimPrefix(ref.Ref, "#/components/schemas/")
+	if schema, ok := g.Schemas()[name]; ok {
+		schema.Properties["errors"] = &Schema{
+			Type:  "array",
+			Items: g.Schema(reflect.TypeFor[shared.ValidationDetail]()),
+		}
+	}
+	return ref
+}
+
+// operationID returns a camel case ID of method and path: GET /users/:id is getUsersById.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	}) {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			b.WriteString("By")
+			segment = name
+		} else if name, ok := strings.CutPrefix(segment, "*"); ok {
+			segment = name
+		}
+		if segment != "" {
+			b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+		}
+	}
+	return b.String()
+}
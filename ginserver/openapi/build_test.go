@@ -0,0 +1,162 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver/openapi"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	Zip string `json:"zip" validate:"len=5,numeric"`
+}
+
+type user struct {
+	ID        shared.ID            `json:"id"`
+	Email     string               `json:"email" validate:"required,email"`
+	Age       int                  `json:"age,omitempty" validate:"gte=18,lt=150"`
+	Role      string               `json:"role" validate:"oneof=admin member"`
+	Tags      []string             `json:"tags" validate:"max=5,dive,min=2"`
+	Name      shared.LocalizedText `json:"name" validate:"languages=en th"`
+	Address   *address             `json:"address"`
+	Manager   *user                `json:"manager,omitempty"`
+	CreatedAt time.Time            `json:"createdAt"`
+	internal  string
+}
+
+type listUsers struct {
+	shared.Pagination
+	Role   string `form:"role"`
+	Tenant string `header:"X-Tenant" validate:"required"`
+}
+
+type updateUser struct {
+	ID    shared.ID `uri:"id" json:"-"`
+	Email string    `json:"email" validate:"required,email"`
+}
+
+func schemaJSON(t *testing.T, schema any) string {
+	t.Helper()
+	b, err := json.Marshal(schema)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestGeneratorSchema(t *testing.T) {
+	g := openapi.NewGenerator()
+	require.Equal(t, "#/components/schemas/user", g.Schema(reflect.TypeFor[*user]()).Ref)
+
+	require.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "description": "hashid"},
+			"email": {"type": "string", "format": "email"},
+			"age": {"type": "integer", "format": "int64", "minimum": 18, "exclusiveMaximum": 150},
+			"role": {"type": "string", "enum": ["admin", "member"]},
+			"tags": {"type": "array", "maxItems": 5, "items": {"type": "string", "minLength": 2}},
+			"name": {
+				"type": "object",
+				"description": "texts by language tag",
+				"additionalProperties": {"type": "string"},
+				"required": ["en", "th"]
+			},
+			"address": {"$ref": "#/components/schemas/address"},
+			"manager": {"$ref": "#/components/schemas/user"},
+			"createdAt": {"type": "string", "format": "date-time"}
+		},
+		"required": ["email"]
+	}`, schemaJSON(t, g.Schemas()["user"]))
+	require.JSONEq(t, `{
+		"type": "object",
+		"properties": {"zip": {"type": "string", "minLength": 5, "maxLength": 5, "pattern": "^[-+]?[0-9]+(?:\\.[0-9]+)?$"}}
+	}`, schemaJSON(t, g.Schemas()["address"]))
+
+	g.Schema(reflect.TypeFor[shared.ListPage[user]]())
+	require.Contains(t, g.Schemas(), "ListPageUser")
+}
+
+func TestGeneratorDefinitionsAreCopied(t *testing.T) {
+	type product struct {
+		Name        shared.LocalizedText `json:"name" validate:"dive,max=10"`
+		Description shared.LocalizedText `json:"description" validate:"dive,max=500"`
+		Summary     shared.LocalizedText `json:"summary"`
+	}
+	g := openapi.NewGenerator()
+	g.Schema(reflect.TypeFor[product]())
+
+	properties := g.Schemas()["product"].Properties
+	require.Equal(t, 10, *properties["name"].AdditionalProperties.MaxLength)
+	require.Equal(t, 500, *properties["description"].AdditionalProperties.MaxLength)
+	require.Nil(t, properties["summary"].AdditionalProperties.MaxLength)
+	require.Nil(t, g.Schema(reflect.TypeFor[shared.LocalizedText]()).AdditionalProperties.MaxLength)
+}
+
+func TestBuild(t *testing.T) {
+	doc := openapi.Build(openapi.Info{Title: "users", Version: "1.0.0"}, []openapi.Route{
+		{
+			Method:   http.MethodGet,
+			Path:     "/users",
+			Summary:  "List users",
+			Tags:     []string{"users"},
+			Request:  reflect.TypeFor[listUsers](),
+			Response: reflect.TypeFor[shared.ListPage[user]](),
+		},
+		{
+			Method:   http.MethodPut,
+			Path:     "/users/:id",
+			Tags:     []string{"users"},
+			Request:  reflect.TypeFor[updateUser](),
+			Response: reflect.TypeFor[user](),
+			Errors:   []error{shared.ErrNotFound, shared.ErrPermissionDenied},
+		},
+		{
+			Method: http.MethodDelete,
+			Path:   "/users/:id",
+			Status: http.StatusNoContent,
+			Errors: []error{shared.ErrNotFound},
+		},
+	}, nil)
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+	require.Equal(t, []openapi.Tag{{Name: "users"}}, doc.Tags)
+
+	list := doc.Paths["/users"].Get
+	require.Equal(t, "getUsers", list.OperationID)
+	require.Nil(t, list.RequestBody)
+	require.JSONEq(t, `[
+		{"name": "skip", "in": "query", "schema": {"type": "integer", "format": "int64", "minimum": 0}},
+		{"name": "limit", "in": "query", "schema": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 50}},
+		{"name": "role", "in": "query", "schema": {"type": "string"}},
+		{"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string"}}
+	]`, schemaJSON(t, list.Parameters))
+	require.Equal(t, "#/components/schemas/ListPageUser", list.Responses["200"].Content["application/json"].Schema.Ref)
+	require.Contains(t, list.Responses, "400")
+
+	update := doc.Paths["/users/{id}"].Put
+	require.Equal(t, "putUsersById", update.OperationID)
+	require.JSONEq(t, `[{"name": "id", "in": "path", "required": true, "schema": {"type": "string", "description": "hashid"}}]`,
+		schemaJSON(t, update.Parameters))
+	require.JSONEq(t, `{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}, "required": ["email"]}`,
+		schemaJSON(t, update.RequestBody.Content["application/json"].Schema))
+	require.Equal(t, "Not Found: not_found", update.Responses["404"].Description)
+	require.Equal(t, "#/components/schemas/Problem",
+		update.Responses["403"].Content[shared.ProblemContentType].Schema.Ref)
+
+	remove := doc.Paths["/users/{id}"].Delete
+	require.Equal(t, "No Content", remove.Responses["204"].Description)
+	require.Nil(t, remove.Responses["204"].Content)
+	require.NotContains(t, remove.Responses, "400")
+
+	problem := doc.Components.Schemas["Problem"]
+	require.Equal(t, "#/components/schemas/ValidationDetail", problem.Properties["errors"].Items.Ref)
+
+	b, err := doc.YAML()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(b), "openapi: 3.1.0\n"), string(b))
+	require.Contains(t, string(b), `"404":`)
+}
@@ -0,0 +1,223 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lysoul/gocommon/shared"
+)
+
+// Generator generates the schemas of Go types, following encoding/json.
+// Named struct types are added to the components and referenced.
+type Generator struct {
+	schemas     map[string]*Schema
+	names       map[reflect.Type]string
+	definitions map[reflect.Type]*Schema
+}
+
+// NewGenerator creates a generator knowing the types of shared:
+// shared.ID is a hashid string, shared.LocalizedText a map of texts by language.
+func NewGenerator() *Generator {
+	g := &Generator{
+		schemas:     map[string]*Schema{},
+		names:       map[reflect.Type]string{},
+		definitions: map[reflect.Type]*Schema{},
+	}
+	g.Define(reflect.TypeFor[time.Time](), &Schema{Type: "string", Format: "date-time"})
+	g.Define(reflect.TypeFor[time.Duration](), &Schema{Type: "integer", Description: "nanoseconds"})
+	g.Define(reflect.TypeFor[json.RawMessage](), &Schema{})
+	g.Define(reflect.TypeFor[shared.ID](), &Schema{Type: "string", Description: "hashid"})
+	g.Define(reflect.TypeFor[shared.LocalizedText](), &Schema{
+		Type:                 "object",
+		Description:          "texts by language tag",
+		AdditionalProperties: &Schema{Type: "string"},
+	})
+	g.Define(reflect.TypeFor[shared.LocalizedString](), &Schema{Type: "string"})
+	return g
+}
+
+// Define sets the schema of t, e.g. of types with a custom JSON encoding.
+func (g *Generator) Define(t reflect.Type, schema *Schema) {
+	g.definitions[t] = schema
+}
+
+// Schemas returns the schemas of the named types, by name.
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of t, a reference for named struct types.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[shared.Error]() {
+		// errors marshal to an ErrorResponse
+		t = reflect.TypeFor[shared.ErrorResponse]()
+	}
+	if schema, ok := g.definitions[t]; ok {
+		// the tags of fields change the schema, e.g. dive the additional properties
+		return schema.clone()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: intFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: intFormat(t), Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.Struct(t, nil)
+		}
+		return g.ref(t)
+	default:
+		// interfaces, any value
+		return &Schema{}
+	}
+}
+
+// clone returns a deep copy of s.
+func (s *Schema) clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			c.Properties[name] = property.clone()
+		}
+	}
+	c.Required = slices.Clone(s.Required)
+	c.AdditionalProperties = s.AdditionalProperties.clone()
+	c.Items = s.Items.clone()
+	c.Enum = slices.Clone(s.Enum)
+	return &c
+}
+
+func (g *Generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.name(t)
+		g.names[t] = name
+		// set before generating, for recursive types
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.Struct(t, nil)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// name returns the component name of t: its name, with the names of the type
+// arguments of generic types appended (ListPage[pkg.User] is ListPageUser).
+// Types with the same name in different packages are numbered.
+func (g *Generator) name(t reflect.Type) string {
+	base, args, _ := strings.Cut(t.Name(), "[")
+	var b strings.Builder
+	b.WriteString(base)
+	for _, arg := range strings.FieldsFunc(strings.TrimSuffix(args, "]"), func(r rune) bool {
+		return r == ',' || r == '[' || r == ']'
+	}) {
+		arg = arg[strings.LastIndexAny(arg, "./*")+1:]
+		if arg != "" {
+			b.WriteString(strings.ToUpper(arg[:1]) + arg[1:])
+		}
+	}
+	name := b.String()
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+		name = b.String() + strconv.Itoa(i)
+	}
+}
+
+// Struct returns the object schema of the JSON fields of t,
+// the fields for which skip returns true are left out.
+func (g *Generator) Struct(t reflect.Type, skip func(reflect.StructField) bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(schema, t, skip)
+	return schema
+}
+
+func (g *Generator) fields(schema *Schema, t reflect.Type, skip func(reflect.StructField) bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if skip != nil && skip(field) {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(schema, embedded, skip)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, required := g.Field(field)
+		if strings.Contains(opts, "string") && property.Type != "" {
+			property = &Schema{Type: "string", Description: property.Description}
+		}
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// Field returns the schema of field with the constraints of its validate and binding tags,
+// and whether the field is required.
+func (g *Generator) Field(field reflect.StructField) (*Schema, bool) {
+	schema := g.Schema(field.Type)
+	required := false
+	for _, key := range []string{"binding", "validate"} {
+		if tag := field.Tag.Get(key); tag != "" {
+			if applyTags(schema, field.Type, tag) {
+				required = true
+			}
+		}
+	}
+	return schema, required
+}
+
+func intFormat(t reflect.Type) string {
+	if t.Bits() == 64 {
+		return "int64"
+	}
+	return "int32"
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
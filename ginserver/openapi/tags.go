@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// formats are the JSON schema formats of validator tags.
+//
+//nolint:gochecknoglobals // read-only table.
+var formats = map[string]string{
+	"email":            "email",
+	"url":              "uri",
+	"http_url":         "uri",
+	"uri":              "uri",
+	"uuid":             "uuid",
+	"uuid3":            "uuid",
+	"uuid4":            "uuid",
+	"uuid5":            "uuid",
+	"uuid_rfc4122":     "uuid",
+	"uuid3_rfc4122":    "uuid",
+	"uuid4_rfc4122":    "uuid",
+	"uuid5_rfc4122":    "uuid",
+	"ipv4":             "ipv4",
+	"ip4_addr":         "ipv4",
+	"ipv6":             "ipv6",
+	"ip6_addr":         "ipv6",
+	"hostname":         "hostname",
+	"hostname_rfc1123": "hostname",
+	"fqdn":             "hostname",
+	"jwt":              "jwt",
+}
+
+// patterns are the regular expressions of validator tags.
+//
+//nolint:gochecknoglobals // read-only table.
+var patterns = map[string]string{
+	"alpha":       `^[a-zA-Z]+$`,
+	"alphanum":    `^[a-zA-Z0-9]+$`,
+	"numeric":     `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":      `^[0-9]+$`,
+	"hexadecimal": `^(0[xX])?[0-9a-fA-F]+$`,
+	"e164":        `^\+[1-9]?[0-9]{7,14}$`,
+	"lowercase":   `^[^A-Z]*$`,
+	"uppercase":   `^[^a-z]*$`,
+	"ulid":        `^[0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{26}$`,
+}
+
+// applyTags adds the constraints of a validator tag of a field of type t to schema,
+// and returns whether the field is required. Constraints after dive apply to the
+// items of slices and the values of maps.
+func applyTags(schema *Schema, t reflect.Type, tag string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	required := false
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			items := schema.Items
+			if t.Kind() == reflect.Map {
+				items = schema.AdditionalProperties
+			}
+			if items == nil || (t.Kind() != reflect.Map && t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+				return required
+			}
+			rest := strings.Join(rules[i+1:], ",")
+			// map keys are not constrained
+			if _, after, ok := strings.Cut(rest, "endkeys"); ok {
+				rest = strings.TrimPrefix(after, ",")
+			}
+			applyTags(items, t.Elem(), rest)
+			return required
+		case "min", "max", "len", "gt", "gte", "lt", "lte":
+			applyBound(schema, name, param)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, enumValue(schema, value))
+			}
+		case "eq":
+			if kind(schema) == "string" || kind(schema) == "number" {
+				schema.Const = enumValue(schema, param)
+			}
+		case "unique":
+			schema.UniqueItems = true
+		case "startswith":
+			schema.Pattern = "^" + regexp.QuoteMeta(param)
+		case "endswith":
+			schema.Pattern = regexp.QuoteMeta(param) + "$"
+		case "datetime":
+			if param == "2006-01-02" {
+				schema.Format = "date"
+			}
+		case "languages":
+			schema.Required = append(schema.Required, strings.Fields(param)...)
+		default:
+			if format, ok := formats[name]; ok {
+				schema.Format = format
+			} else if pattern, ok := patterns[name]; ok {
+				schema.Pattern = pattern
+			}
+		}
+	}
+	return required
+}
+
+// applyBound adds the constraint of a size or comparison tag: lengths for strings,
+// item counts for slices and maps, values for numbers.
+func applyBound(schema *Schema, name, param string) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		// e.g. gt without param on time.Time
+		return
+	}
+
+	if kind(schema) == "number" {
+		switch name {
+		case "min", "gte":
+			schema.Minimum = &value
+		case "max", "lte":
+			schema.Maximum = &value
+		case "len":
+			schema.Minimum, schema.Maximum = &value, &value
+		case "gt":
+			schema.ExclusiveMinimum = &value
+		case "lt":
+			schema.ExclusiveMaximum = &value
+		}
+		return
+	}
+
+	n := int(value)
+	var minimum, maximum **int
+	switch kind(schema) {
+	case "string":
+		minimum, maximum = &schema.MinLength, &schema.MaxLength
+	case "array":
+		minimum, maximum = &schema.MinItems, &schema.MaxItems
+	case "object":
+		minimum, maximum = &schema.MinProperties, &schema.MaxProperties
+	default:
+		return
+	}
+	switch name {
+	case "min", "gte":
+		*minimum = ptr(n)
+	case "max", "lte":
+		*maximum = ptr(n)
+	case "len":
+		*minimum, *maximum = ptr(n), ptr(n)
+	case "gt":
+		*minimum = ptr(n + 1)
+	case "lt":
+		*maximum = ptr(n - 1)
+	}
+}
+
+// kind returns the JSON kind of schema, numbers and integers are numbers.
+// It is the kind of the JSON value, shared.ID is an int64 encoded as a string.
+func kind(schema *Schema) string {
+	if schema.Type == "integer" {
+		return "number"
+	}
+	return schema.Type
+}
+
+func enumValue(schema *Schema, value string) any {
+	if kind(schema) == "number" {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package ginserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/ginserver/openapi"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOpenAPI(t *testing.T) {
+	s, err := ginserver.New(ginserver.Config{
+		Mode:        "dev",
+		ServiceName: "orders",
+		OpenAPIPath: "/openapi",
+	}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+
+	service := orderService{}
+	orders := s.Routes().Group("/orders")
+	ginserver.Register(orders, http.MethodPost, "", service.Create, ginserver.Tags("orders"))
+	ginserver.Register(orders, http.MethodPut, "/:id", service.Update,
+		ginserver.Summary("Update an order"), ginserver.Errors(shared.ErrNotFound))
+	ginserver.Register(orders, http.MethodDelete, "/:id", service.Delete)
+	ginserver.Register(orders, http.MethodPost, "/export", service.Export)
+	orders.Handle(http.MethodGet, "/:id/label", []ginserver.RouteOption{
+		ginserver.Request(orderURI{}), ginserver.Response(""), ginserver.Deprecated(),
+	}, func(c *gin.Context) {
+		c.JSON(http.StatusOK, "label")
+	})
+
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/7", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code, "registered routes are served")
+
+	w = httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+
+	require.Equal(t, openapi.Info{Title: "orders", Version: "1.0.0"}, doc.Info)
+	require.Equal(t, []string{"/orders", "/orders/export", "/orders/{id}", "/orders/{id}/label"}, keys(doc.Paths))
+	require.Equal(t, []string{"201", "400"}, keys(doc.Paths["/orders"].Post.Responses))
+	require.Equal(t, []string{"202"}, keys(doc.Paths["/orders/export"].Post.Responses))
+	require.Equal(t, []string{"204", "400"}, keys(doc.Paths["/orders/{id}"].Delete.Responses))
+
+	update := doc.Paths["/orders/{id}"].Put
+	require.Equal(t, "Update an order", update.Summary)
+	require.Equal(t, []string{"200", "400", "404"}, keys(update.Responses))
+	require.Equal(t, "#/components/schemas/order", update.Responses["200"].Content["application/json"].Schema.Ref)
+	var params []string
+	for _, p := range update.Parameters {
+		params = append(params, p.In+":"+p.Name)
+	}
+	require.Equal(t, []string{"path:id", "header:X-Tenant", "query:dry_run"}, params)
+	require.Equal(t, []string{"customer"}, update.RequestBody.Content["application/json"].Schema.Required)
+
+	label := doc.Paths["/orders/{id}/label"].Get
+	require.True(t, label.Deprecated)
+	require.Equal(t, "string", label.Responses["200"].Content["application/json"].Schema.Type)
+
+	w = httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	var yamlDoc openapi.Document
+	require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &yamlDoc))
+	require.Equal(t, doc.Info, yamlDoc.Info)
+}
+
+func keys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,367 @@
+package ginserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// specURL is the URL of the document in the schema compiler, schemas are compiled by pointer.
+const specURL = "file:///openapi.json"
+
+// OpenAPISpec is an OpenAPI 3.1 document compiled to validate requests and responses,
+// for APIs written spec first. See OpenAPIValidationMiddleware.
+type OpenAPISpec struct {
+	bases      [][]string
+	operations []*specOperation
+}
+
+type specOperation struct {
+	method    string
+	segments  []string
+	params    []*specParam
+	body      *specContent
+	responses map[string]*specContent
+}
+
+type specParam struct {
+	name     string
+	in       string
+	required bool
+	// types are the JSON types of the schema, to convert the values of the parameter
+	types     []string
+	itemTypes []string
+	schema    *jsonschema.Schema
+}
+
+// specContent is a request body or a response: the schemas by media type range,
+// nil for media types without schema.
+type specContent struct {
+	required bool
+	content  map[string]*jsonschema.Schema
+}
+
+// LoadOpenAPISpec reads and compiles the document name of fsys,
+// e.g. an embed.FS, or os.DirFS for a document on disk.
+func LoadOpenAPISpec(fsys fs.FS, name string) (*OpenAPISpec, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("ginserver: read OpenAPI document: %w", err)
+	}
+	return ParseOpenAPISpec(b)
+}
+
+// ParseOpenAPISpec compiles doc, an OpenAPI 3.1 document in JSON or YAML.
+// Only local references (#/components/...) are resolved.
+func ParseOpenAPISpec(doc []byte) (*OpenAPISpec, error) {
+	// JSON is YAML, numbers are kept as json.Number by the schema loader
+	var raw any
+	if err := yaml.Unmarshal(doc, &raw); err != nil {
+		return nil, fmt.Errorf("ginserver: parse OpenAPI document: %w", err)
+	}
+	b, err := json.Marshal(stringKeys(raw))
+	if err != nil {
+		return nil, fmt.Errorf("ginserver: parse OpenAPI document: %w", err)
+	}
+	root, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("ginserver: parse OpenAPI document: %w", err)
+	}
+	document, _ := root.(map[string]any)
+	if version, _ := document["openapi"].(string); !strings.HasPrefix(version, "3.1.") {
+		return nil, fmt.Errorf("ginserver: OpenAPI %q is not supported, only 3.1", version)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	if err := compiler.AddResource(specURL, document); err != nil {
+		return nil, fmt.Errorf("ginserver: compile OpenAPI document: %w", err)
+	}
+	c := &specCompiler{document: document, compiler: compiler}
+	spec := &OpenAPISpec{bases: c.bases()}
+	if err := c.operations(spec); err != nil {
+		return nil, fmt.Errorf("ginserver: compile OpenAPI document: %w", err)
+	}
+	return spec, nil
+}
+
+// operation returns the operation of method and path with the values of its path parameters,
+// nil if the document has none. Paths without parameters are matched first.
+func (s *OpenAPISpec) operation(method, path string) (*specOperation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var (
+		match    *specOperation
+		values   map[string]string
+		literals = -1
+	)
+	for _, base := range s.bases {
+		if len(segments) < len(base) || !slices.Equal(segments[:len(base)], base) {
+			continue
+		}
+		rest := segments[len(base):]
+		for _, op := range s.operations {
+			if op.method != method || len(op.segments) != len(rest) {
+				continue
+			}
+			if params, n, ok := matchSegments(op.segments, rest); ok && n > literals {
+				match, values, literals = op, params, n
+			}
+		}
+	}
+	return match, values
+}
+
+// matchSegments matches the segments of a path against a template, returning the values
+// of the parameters and the number of literal segments.
+func matchSegments(template, segments []string) (map[string]string, int, bool) {
+	params := map[string]string{}
+	literals := 0
+	for i, segment := range template {
+		if name, ok := strings.CutPrefix(segment, "{"); ok && strings.HasSuffix(name, "}") {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, 0, false
+			}
+			params[strings.TrimSuffix(name, "}")] = value
+			continue
+		}
+		if segment != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+type specCompiler struct {
+	document map[string]any
+	compiler *jsonschema.Compiler
+}
+
+// bases returns the paths of the server URLs, the paths of the document are relative to them.
+func (c *specCompiler) bases() [][]string {
+	var bases [][]string
+	servers, _ := c.document["servers"].([]any)
+	for _, server := range servers {
+		server, _ := server.(map[string]any)
+		rawURL, _ := server["url"].(string)
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		if path := strings.Trim(u.Path, "/"); path != "" {
+			bases = append(bases, strings.Split(path, "/"))
+		}
+	}
+	if len(bases) == 0 {
+		bases = append(bases, nil)
+	}
+	return bases
+}
+
+func (c *specCompiler) operations(spec *OpenAPISpec) error {
+	paths, _ := c.document["paths"].(map[string]any)
+	for _, path := range sortedKeys(paths) {
+		pathPtr := "/paths/" + escapePointer(path)
+		item, itemPtr := c.resolve(paths[path], pathPtr)
+		inherited, err := c.params(nil, item["parameters"], itemPtr+"/parameters")
+		if err != nil {
+			return err
+		}
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+			if _, ok := item[method].(map[string]any); !ok {
+				continue
+			}
+			op, err := c.operation(item[method].(map[string]any), itemPtr+"/"+method, inherited)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			op.method = strings.ToUpper(method)
+			op.segments = strings.Split(strings.Trim(path, "/"), "/")
+			spec.operations = append(spec.operations, op)
+		}
+	}
+	return nil
+}
+
+func (c *specCompiler) operation(operation map[string]any, ptr string, inherited []*specParam) (*specOperation, error) {
+	params, err := c.params(inherited, operation["parameters"], ptr+"/parameters")
+	if err != nil {
+		return nil, err
+	}
+	op := &specOperation{params: params, responses: map[string]*specContent{}}
+
+	if _, ok := operation["requestBody"]; ok {
+		body, bodyPtr := c.resolve(operation["requestBody"], ptr+"/requestBody")
+		if op.body, err = c.content(body, bodyPtr); err != nil {
+			return nil, err
+		}
+		op.body.required, _ = body["required"].(bool)
+	}
+
+	responses, _ := operation["responses"].(map[string]any)
+	for status := range responses {
+		response, responsePtr := c.resolve(responses[status], ptr+"/responses/"+escapePointer(status))
+		if op.responses[strings.ToUpper(status)], err = c.content(response, responsePtr); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// params compiles parameters, which override the inherited ones with the same name and location.
+func (c *specCompiler) params(inherited []*specParam, parameters any, ptr string) ([]*specParam, error) {
+	params := slices.Clone(inherited)
+	list, _ := parameters.([]any)
+	for i := range list {
+		param, paramPtr := c.resolve(list[i], ptr+"/"+strconv.Itoa(i))
+		p := &specParam{}
+		p.name, _ = param["name"].(string)
+		p.in, _ = param["in"].(string)
+		p.required, _ = param["required"].(bool)
+		if p.in == "header" {
+			switch http.CanonicalHeaderKey(p.name) {
+			case "Accept", "Content-Type", "Authorization":
+				// ignored by OpenAPI
+				continue
+			}
+		}
+		if _, ok := param["schema"]; ok {
+			schema, err := c.compile(paramPtr + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			p.schema = schema
+			definition, _ := c.resolve(param["schema"], paramPtr+"/schema")
+			p.types = c.types(definition)
+			if items, ok := definition["items"]; ok {
+				definition, _ := c.resolve(items, "")
+				p.itemTypes = c.types(definition)
+			}
+		}
+		params = slices.DeleteFunc(params, func(other *specParam) bool {
+			return other.in == p.in && strings.EqualFold(other.name, p.name)
+		})
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// content compiles the schemas of the content of a request body or a response.
+func (c *specCompiler) content(v map[string]any, ptr string) (*specContent, error) {
+	content := &specContent{content: map[string]*jsonschema.Schema{}}
+	mediaTypes, _ := v["content"].(map[string]any)
+	for mediaType := range mediaTypes {
+		var schema *jsonschema.Schema
+		if definition, _ := mediaTypes[mediaType].(map[string]any); definition["schema"] != nil {
+			var err error
+			schema, err = c.compile(ptr + "/content/" + escapePointer(mediaType) + "/schema")
+			if err != nil {
+				return nil, err
+			}
+		}
+		content.content[mediaType] = schema
+	}
+	return content, nil
+}
+
+func (c *specCompiler) compile(ptr string) (*jsonschema.Schema, error) {
+	return c.compiler.Compile(specURL + "#" + (&url.URL{Fragment: ptr}).EscapedFragment())
+}
+
+// resolve follows the local references of v, returning the object and its pointer.
+func (c *specCompiler) resolve(v any, ptr string) (map[string]any, string) {
+	object, _ := v.(map[string]any)
+	for range 32 {
+		ref, ok := object["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			break
+		}
+		ptr = strings.TrimPrefix(ref, "#")
+		var target any = c.document
+		for _, token := range strings.Split(ptr[1:], "/") {
+			container, _ := target.(map[string]any)
+			target = container[unescapePointer(token)]
+		}
+		object, _ = target.(map[string]any)
+	}
+	return object, ptr
+}
+
+// types returns the JSON types of a schema.
+func (c *specCompiler) types(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// stringKeys converts the maps decoded from YAML to maps with string keys, e.g. status codes.
+func stringKeys(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+//nolint:gochecknoglobals // read-only replacers.
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+func unescapePointer(token string) string {
+	return pointerUnescaper.Replace(token)
+}
@@ -0,0 +1,506 @@
+package ginserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"go.uber.org/zap"
+)
+
+type openAPIValidationConfig struct {
+	responses bool
+}
+
+// OpenAPIValidationOption configures OpenAPIValidationMiddleware.
+type OpenAPIValidationOption func(*openAPIValidationConfig)
+
+// WithResponseValidation validates the responses too: responses that do not match the
+// document are logged and replaced by a 500 problem with the violations.
+// Responses are buffered, use it in development and tests.
+func WithResponseValidation(enabled bool) OpenAPIValidationOption {
+	return func(c *openAPIValidationConfig) {
+		c.responses = enabled
+	}
+}
+
+// OpenAPIValidationMiddleware validates the requests of the operations of spec:
+// the path, query, header and cookie parameters, the content type and the JSON schema of the body.
+// Violations are written as the problem details of a *shared.ValidationError (shared.ErrValidationFailed).
+// Requests of paths and methods that are not in the document are not validated.
+//
+// It checks the content type of the body, replacing WithContentType.
+func OpenAPIValidationMiddleware(spec *OpenAPISpec, opts ...OpenAPIValidationOption) gin.HandlerFunc {
+	config := &openAPIValidationConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return func(c *gin.Context) {
+		op, pathParams := spec.operation(c.Request.Method, c.Request.URL.EscapedPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+		language := shared.LanguageFromContext(c.Request.Context())
+
+		details := validateParams(c, op, pathParams, language)
+		bodyDetails, err := validateRequestBody(c, op.body, language)
+		if err != nil {
+			shared.ErrorToHTTP(c, err)
+			return
+		}
+		details = append(details, bodyDetails...)
+		if len(details) > 0 {
+			shared.ErrorToHTTP(c, &shared.ValidationError{Details: details})
+			return
+		}
+
+		if !config.responses {
+			c.Next()
+			return
+		}
+		validateResponse(c, op, language)
+	}
+}
+
+func validateParams(c *gin.Context, op *specOperation, pathParams map[string]string, language string) []shared.ValidationDetail {
+	var details []shared.ValidationDetail
+	query := c.Request.URL.Query()
+	for _, param := range op.params {
+		var values []string
+		switch param.in {
+		case "path":
+			if value, ok := pathParams[param.name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[param.name]
+		case "header":
+			values = c.Request.Header.Values(param.name)
+		case "cookie":
+			if cookie, err := c.Request.Cookie(param.name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+		if len(values) == 0 {
+			if param.required {
+				details = append(details, shared.NewValidationDetail(param.name, "required", "", language))
+			}
+			continue
+		}
+		if param.schema == nil {
+			continue
+		}
+
+		var value any
+		if slices.Contains(param.types, "array") {
+			if len(values) == 1 {
+				values = strings.Split(values[0], ",")
+			}
+			items := make([]any, len(values))
+			for i, v := range values {
+				items[i] = paramValue(v, param.itemTypes)
+			}
+			value = items
+		} else {
+			value = paramValue(values[0], param.types)
+		}
+		if err := param.schema.Validate(value); err != nil {
+			details = append(details, schemaDetails(err, []string{param.name}, language)...)
+		}
+	}
+	return details
+}
+
+// paramValue converts the value of a parameter to the JSON type of its schema,
+// values that can't be converted are left as strings and fail the type check.
+func paramValue(value string, types []string) any {
+	switch {
+	case slices.Contains(types, "integer"), slices.Contains(types, "number"):
+		if _, ok := new(big.Rat).SetString(value); ok {
+			return json.Number(value)
+		}
+	case slices.Contains(types, "boolean"):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case slices.Contains(types, "null"):
+		if value == "" {
+			return nil
+		}
+	}
+	return value
+}
+
+func validateRequestBody(c *gin.Context, body *specContent, language string) ([]shared.ValidationDetail, error) {
+	if body == nil {
+		return nil, nil
+	}
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength == 0 {
+		if body.required {
+			return []shared.ValidationDetail{shared.NewValidationDetail("", "required", "", language)}, nil
+		}
+		return nil, nil
+	}
+
+	mediaType, schema, ok := body.match(c.Request.Header.Get(headers.ContentType))
+	if !ok {
+		return []shared.ValidationDetail{
+			shared.NewValidationDetail(headers.ContentType, "oneof", strings.Join(sortedKeys(body.content), " "), language),
+		}, nil
+	}
+	if schema == nil || !isJSON(mediaType) {
+		return nil, nil
+	}
+
+	config := newBindConfig(c, nil)
+	reader := io.Reader(c.Request.Body)
+	if config.maxBodySize > 0 {
+		reader = http.MaxBytesReader(c.Writer, c.Request.Body, config.maxBodySize)
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, shared.NewError(shared.ErrRequestTooLarge, "",
+				fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit))
+		}
+		return nil, err
+	}
+	// handlers bind the body again
+	c.Request.Body = io.NopCloser(bytes.NewReader(b))
+
+	return validateJSON(schema, b, language), nil
+}
+
+func validateJSON(schema *jsonschema.Schema, b []byte, language string) []shared.ValidationDetail {
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return []shared.ValidationDetail{shared.NewValidationDetail("", "syntax", "JSON", language)}
+	}
+	if err := schema.Validate(value); err != nil {
+		return schemaDetails(err, nil, language)
+	}
+	return nil
+}
+
+// match returns the media type range of content matching contentType, and its schema.
+func (s *specContent) match(contentType string) (string, *jsonschema.Schema, bool) {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", nil, false
+	}
+	// the most specific range matches: text/plain, then text/*, then */*
+	for _, candidate := range []string{t, t[:strings.Index(t, "/")+1] + "*", "*/*"} {
+		for mediaType, schema := range s.content {
+			if m, _, err := mime.ParseMediaType(mediaType); err == nil && m == candidate {
+				return t, schema, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validateResponse buffers the response of the handlers and checks its status,
+// content type and body before sending it.
+func validateResponse(c *gin.Context, op *specOperation, language string) {
+	w := &specResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+	c.Writer = w
+	// on panics too, the buffered response is discarded and RecoveryMiddleware responds
+	defer func() {
+		c.Writer = w.ResponseWriter
+	}()
+	c.Next()
+	c.Writer = w.ResponseWriter
+	if w.passthrough || (!w.written && len(c.Errors) > 0) {
+		// the errors are written by an outer ErrorMiddleware
+		w.flush()
+		return
+	}
+
+	details := w.validate(op, c.Request.Method, language)
+	if len(details) == 0 {
+		w.flush()
+		return
+	}
+
+	err := &shared.ValidationError{Details: details}
+	zap.L().Error("response does not match the OpenAPI document",
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", w.status),
+		zap.Error(err))
+	p := shared.HTTPErrors().Problem(c, err)
+	p.Status = http.StatusInternalServerError
+	p.Title = http.StatusText(p.Status)
+	p.Detail = "response does not match the OpenAPI document"
+	c.Writer.Header().Del(headers.ContentLength)
+	shared.WriteProblem(c, p)
+}
+
+// specResponseWriter buffers a response until it is validated.
+// Flushed (streamed) and hijacked responses are passed through without validation.
+type specResponseWriter struct {
+	gin.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	written     bool
+	passthrough bool
+}
+
+func (w *specResponseWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *specResponseWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *specResponseWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *specResponseWriter) WriteString(s string) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *specResponseWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *specResponseWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *specResponseWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+// Flush sends the buffered response, streamed responses are not validated.
+func (w *specResponseWriter) Flush() {
+	if !w.passthrough {
+		w.flush()
+		w.passthrough = true
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *specResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *specResponseWriter) flush() {
+	if w.passthrough {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.written {
+		w.ResponseWriter.WriteHeaderNow()
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+// validate returns the violations of the response of op.
+func (w *specResponseWriter) validate(op *specOperation, method, language string) []shared.ValidationDetail {
+	status := strconv.Itoa(w.status)
+	response, ok := op.responses[status]
+	if !ok {
+		response, ok = op.responses[status[:1]+"XX"]
+	}
+	if !ok {
+		response, ok = op.responses["default"]
+	}
+	if !ok {
+		return []shared.ValidationDetail{
+			shared.NewValidationDetail("status", "oneof", strings.Join(sortedKeys(op.responses), " "), language),
+		}
+	}
+	if w.body.Len() == 0 || method == http.MethodHead || len(response.content) == 0 {
+		return nil
+	}
+
+	mediaType, schema, ok := response.match(w.Header().Get(headers.ContentType))
+	if !ok {
+		return []shared.ValidationDetail{
+			shared.NewValidationDetail(headers.ContentType, "oneof", strings.Join(sortedKeys(response.content), " "), language),
+		}
+	}
+	if schema == nil || !isJSON(mediaType) {
+		return nil
+	}
+	return validateJSON(schema, w.body.Bytes(), language)
+}
+
+// schemaDetails describes the errors of a JSON schema validation with the rules
+// of the validator tags, e.g. minLength is min. Fields are prefixed with prefix.
+func schemaDetails(err error, prefix []string, language string) []shared.ValidationDetail {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []shared.ValidationDetail{shared.NewValidationDetail(strings.Join(prefix, "."), "invalid", "", language)}
+	}
+
+	var details []shared.ValidationDetail
+	var walk func(err *jsonschema.ValidationError)
+	walk = func(err *jsonschema.ValidationError) {
+		switch err.ErrorKind.(type) {
+		case *kind.Schema, *kind.Reference, *kind.Group, *kind.AllOf:
+			for _, cause := range err.Causes {
+				walk(cause)
+			}
+			return
+		}
+		path := slices.Concat(prefix, err.InstanceLocation)
+		details = append(details, schemaErrorDetails(err.ErrorKind, path, language)...)
+	}
+	walk(validationErr)
+	return details
+}
+
+func schemaErrorDetails(errorKind jsonschema.ErrorKind, path []string, language string) []shared.ValidationDetail {
+	field := fieldPath(strings.Join(path, "."))
+	detail := func(kind reflect.Kind, rule, param string) []shared.ValidationDetail {
+		return []shared.ValidationDetail{shared.NewValidationDetailOf(kind, field, rule, param, language)}
+	}
+	properties := func(rule, param string, names []string) []shared.ValidationDetail {
+		details := make([]shared.ValidationDetail, len(names))
+		for i, name := range names {
+			details[i] = shared.NewValidationDetail(fieldPath(strings.Join(slices.Concat(path, []string{name}), ".")), rule, param, language)
+		}
+		return details
+	}
+
+	switch k := errorKind.(type) {
+	case *kind.Required:
+		return properties("required", "", k.Missing)
+	case *kind.DependentRequired:
+		return properties("required_with", k.Prop, k.Missing)
+	case *kind.Dependency:
+		return properties("required_with", k.Prop, k.Missing)
+	case *kind.AdditionalProperties:
+		return properties("unknown", "", k.Properties)
+	case *kind.FalseSchema:
+		return detail(reflect.Invalid, "unknown", "")
+	case *kind.Type:
+		return detail(reflect.Invalid, "type", strings.Join(k.Want, ", "))
+	case *kind.Enum:
+		values := make([]string, len(k.Want))
+		for i, v := range k.Want {
+			values[i] = fmt.Sprint(v)
+		}
+		return detail(reflect.Invalid, "oneof", strings.Join(values, " "))
+	case *kind.Const:
+		return detail(reflect.Invalid, "eq", fmt.Sprint(k.Want))
+	case *kind.Format:
+		rule, param := formatRule(k.Want)
+		return detail(reflect.String, rule, param)
+	case *kind.Pattern:
+		return detail(reflect.String, "pattern", k.Want)
+	case *kind.MinLength:
+		return detail(reflect.String, "min", strconv.Itoa(k.Want))
+	case *kind.MaxLength:
+		return detail(reflect.String, "max", strconv.Itoa(k.Want))
+	case *kind.MinItems:
+		return detail(reflect.Slice, "min", strconv.Itoa(k.Want))
+	case *kind.MaxItems:
+		return detail(reflect.Slice, "max", strconv.Itoa(k.Want))
+	case *kind.MinProperties:
+		return detail(reflect.Map, "min", strconv.Itoa(k.Want))
+	case *kind.MaxProperties:
+		return detail(reflect.Map, "max", strconv.Itoa(k.Want))
+	case *kind.UniqueItems:
+		return detail(reflect.Slice, "unique", "")
+	case *kind.Minimum:
+		return detail(reflect.Invalid, "min", ratString(k.Want))
+	case *kind.Maximum:
+		return detail(reflect.Invalid, "max", ratString(k.Want))
+	case *kind.ExclusiveMinimum:
+		return detail(reflect.Invalid, "gt", ratString(k.Want))
+	case *kind.ExclusiveMaximum:
+		return detail(reflect.Invalid, "lt", ratString(k.Want))
+	case *kind.MultipleOf:
+		return detail(reflect.Invalid, "multipleOf", ratString(k.Want))
+	default:
+		// e.g. oneOf, not: the value is invalid
+		rule := "invalid"
+		if keywords := errorKind.KeywordPath(); len(keywords) > 0 {
+			rule = keywords[len(keywords)-1]
+		}
+		return detail(reflect.Invalid, rule, "")
+	}
+}
+
+// formatRule returns the validator tag of a JSON schema format, "format" if there is none.
+func formatRule(format string) (string, string) {
+	switch format {
+	case "email", "idn-email":
+		return "email", ""
+	case "uri", "iri":
+		return "uri", ""
+	case "hostname", "idn-hostname":
+		return "hostname", ""
+	case "uuid", "ipv4", "ipv6":
+		return format, ""
+	default:
+		return "format", format
+	}
+}
+
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	f, _ := r.Float64()
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
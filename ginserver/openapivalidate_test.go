@@ -0,0 +1,299 @@
+package ginserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+const petsSpec = `
+openapi: 3.1.0
+info:
+  title: pets
+  version: 1.0.0
+servers:
+  - url: https://example.com/api
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, minimum: 1, maximum: 50}
+        - name: tags
+          in: query
+          schema: {type: array, items: {type: string, enum: [cat, dog]}}
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: '#/components/schemas/Pet'}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        201:
+          description: Created
+        4XX:
+          $ref: '#/components/responses/Problem'
+  /pets/{id}:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+      - name: X-Tenant
+        in: header
+        required: true
+        schema: {type: string}
+    get:
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+  /pets/mine:
+    get:
+      responses:
+        204:
+          description: No Content
+components:
+  parameters:
+    ID:
+      name: id
+      in: path
+      required: true
+      schema: {type: integer, exclusiveMinimum: 0}
+  responses:
+    Problem:
+      description: Problem
+      content:
+        application/problem+json:
+          schema: {type: object}
+  schemas:
+    Pet:
+      type: object
+      additionalProperties: false
+      required: [name, kind]
+      properties:
+        id: {type: integer}
+        name: {type: string, minLength: 2}
+        kind: {type: string, enum: [cat, dog]}
+        email: {type: string, format: email}
+        toys:
+          type: array
+          maxItems: 2
+          items:
+            type: object
+            properties:
+              price: {type: number, minimum: 0}
+`
+
+func petsRouter(t *testing.T, handler gin.HandlerFunc, opts ...ginserver.OpenAPIValidationOption) *gin.Engine {
+	t.Helper()
+	spec, err := ginserver.LoadOpenAPISpec(fstest.MapFS{"openapi.yaml": {Data: []byte(petsSpec)}}, "openapi.yaml")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginserver.ErrorMiddleware(), ginserver.OpenAPIValidationMiddleware(spec, opts...))
+	router.NoRoute(handler)
+	return router
+}
+
+func doRequest(router http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func problemDetails(t *testing.T, w *httptest.ResponseRecorder) []shared.ValidationDetail {
+	t.Helper()
+	var p struct {
+		Code   string                    `json:"code"`
+		Errors []shared.ValidationDetail `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p), w.Body.String())
+	require.Equal(t, shared.ErrValidationFailed.Error(), p.Code)
+	return p.Errors
+}
+
+func TestOpenAPIValidationRequests(t *testing.T) {
+	var body string
+	router := petsRouter(t, func(c *gin.Context) {
+		b, err := c.GetRawData()
+		require.NoError(t, err)
+		body = string(b)
+		c.Status(http.StatusNoContent)
+	})
+
+	pet := `{"name":"rex","kind":"dog","toys":[{"price":1.5}]}`
+	require.Equal(t, http.StatusNoContent, doRequest(router, http.MethodPost, "/api/pets", "application/json", pet).Code)
+	require.Equal(t, pet, body, "the body is kept for the handlers")
+	require.Equal(t, http.StatusNoContent, doRequest(router, http.MethodGet, "/api/pets?limit=10&tags=cat,dog", "", "").Code)
+	require.Equal(t, http.StatusNoContent, doRequest(router, http.MethodGet, "/api/pets/mine", "", "").Code,
+		"paths without parameters are matched first")
+	require.Equal(t, http.StatusNoContent, doRequest(router, http.MethodGet, "/api/other", "", "").Code,
+		"undocumented paths are not validated")
+	require.Equal(t, http.StatusNoContent, doRequest(router, http.MethodGet, "/pets?limit=0", "", "").Code,
+		"paths are relative to the servers")
+
+	w := doRequest(router, http.MethodGet, "/api/pets?limit=0&tags=cat,fish", "", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, []shared.ValidationDetail{
+		{Field: "limit", JSONPointer: "/limit", Rule: "min", Param: "1", Message: "must be at least 1"},
+		{Field: "tags[1]", JSONPointer: "/tags/1", Rule: "oneof", Param: "cat dog", Message: "must be one of cat, dog"},
+	}, problemDetails(t, w))
+
+	w = doRequest(router, http.MethodGet, "/api/pets/abc", "", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, []shared.ValidationDetail{
+		{Field: "id", JSONPointer: "/id", Rule: "type", Param: "integer", Message: "must be of type integer"},
+	}, problemDetails(t, w))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/pets/0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, []string{"id", "X-Tenant"}, fields(problemDetails(t, w)))
+
+	w = doRequest(router, http.MethodPost, "/api/pets", "application/json",
+		`{"name":"r","email":"rex","toys":[{},{"price":-1},{}],"age":3}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.ElementsMatch(t, []shared.ValidationDetail{
+		{Field: "kind", JSONPointer: "/kind", Rule: "required", Message: "is required"},
+		{Field: "age", JSONPointer: "/age", Rule: "unknown", Message: "is not allowed"},
+		{Field: "name", JSONPointer: "/name", Rule: "min", Param: "2", Message: "must be at least 2 characters long"},
+		{Field: "email", JSONPointer: "/email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "toys", JSONPointer: "/toys", Rule: "max", Param: "2", Message: "must contain at most 2 items"},
+		{Field: "toys[1].price", JSONPointer: "/toys/1/price", Rule: "min", Param: "0", Message: "must be at least 0"},
+	}, problemDetails(t, w))
+
+	w = doRequest(router, http.MethodPost, "/api/pets", "text/plain", pet)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, []shared.ValidationDetail{{
+		Field: "Content-Type", JSONPointer: "/Content-Type", Rule: "oneof", Param: "application/json",
+		Message: "must be one of application/json",
+	}}, problemDetails(t, w))
+
+	w = doRequest(router, http.MethodPost, "/api/pets", "application/json", `{"name":`)
+	require.Equal(t, "syntax", problemDetails(t, w)[0].Rule)
+	w = doRequest(router, http.MethodPost, "/api/pets", "application/json", "")
+	require.Equal(t, "required", problemDetails(t, w)[0].Rule)
+}
+
+func fields(details []shared.ValidationDetail) []string {
+	fields := make([]string, len(details))
+	for i, detail := range details {
+		fields[i] = detail.Field
+	}
+	return fields
+}
+
+func TestOpenAPIValidationResponses(t *testing.T) {
+	var response func(c *gin.Context)
+	router := petsRouter(t, func(c *gin.Context) {
+		response(c)
+	}, ginserver.WithResponseValidation(true))
+
+	response = func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{{"name": "rex", "kind": "dog"}})
+	}
+	w := doRequest(router, http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"name":"rex","kind":"dog"}]`, w.Body.String())
+
+	response = func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{{"name": "rex"}})
+	}
+	w = doRequest(router, http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, shared.ProblemContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, []string{"[0].kind"}, fields(problemDetails(t, w)))
+
+	response = func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+	}
+	w = doRequest(router, http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, []shared.ValidationDetail{
+		{Field: "status", JSONPointer: "/status", Rule: "oneof", Param: "200", Message: "must be one of 200"},
+	}, problemDetails(t, w))
+
+	// errors are validated against the 4XX response
+	response = func(c *gin.Context) {
+		_ = c.Error(shared.ErrNotFound)
+	}
+	w = doRequest(router, http.MethodPost, "/api/pets", "application/json", `{"name":"rex","kind":"dog"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// streamed responses are not validated
+	response = func(c *gin.Context) {
+		c.String(http.StatusOK, "not json")
+		c.Writer.Flush()
+	}
+	w = doRequest(router, http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "not json", w.Body.String())
+}
+
+func TestParseOpenAPISpec(t *testing.T) {
+	_, err := ginserver.ParseOpenAPISpec([]byte(`{"openapi":"3.0.3","info":{"title":"x","version":"1"},"paths":{}}`))
+	require.ErrorContains(t, err, `OpenAPI "3.0.3" is not supported`)
+
+	_, err = ginserver.ParseOpenAPISpec([]byte(`{"openapi":"3.1.0","paths":{"/x":{"get":{"parameters":[
+		{"name":"x","in":"query","schema":{"type":"integer","minimum":"one"}}]}}}}`))
+	require.ErrorContains(t, err, "GET /x")
+}
+
+func TestOpenAPISpecServer(t *testing.T) {
+	spec, err := ginserver.ParseOpenAPISpec([]byte(petsSpec))
+	require.NoError(t, err)
+	newServer := func(config ginserver.Config) *ginserver.Server {
+		s, err := ginserver.New(config, ginserver.WithLogger(otelzap.New(zap.NewNop())), ginserver.WithOpenAPISpec(spec))
+		require.NoError(t, err)
+		s.Router().Use(ginserver.ErrorMiddleware())
+		s.Router().GET("/api/pets", func(c *gin.Context) {
+			c.JSON(http.StatusOK, []gin.H{{"name": "rex", "kind": "bird"}})
+		})
+		return s
+	}
+
+	s := newServer(ginserver.Config{Mode: "dev"})
+	w := doRequest(s.Handler(), http.MethodGet, "/api/pets?limit=100", "", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, []string{"limit"}, fields(problemDetails(t, w)))
+
+	w = doRequest(s.Handler(), http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusOK, w.Code, "responses are not validated by default")
+
+	s = newServer(ginserver.Config{Mode: "dev", OpenAPIValidateResponses: true})
+	w = doRequest(s.Handler(), http.MethodGet, "/api/pets", "", "")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestInvalidOpenAPISpecConfig(t *testing.T) {
+	_, err := ginserver.New(ginserver.Config{Mode: "dev", OpenAPISpec: filepath.Join(t.TempDir(), "openapi.yaml")},
+		ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.ErrorContains(t, err, "read OpenAPI document", "a typo must not disable validation")
+}
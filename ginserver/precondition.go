@@ -0,0 +1,146 @@
+package ginserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+)
+
+// ResourceVersion is the current version of the resource targeted by a request.
+// Set ETag, LastModified or both, leave both empty if the resource does not exist.
+// If-Match uses the strong comparison: weak etags never match it, only If-Unmodified-Since does.
+type ResourceVersion struct {
+	ETag         string
+	LastModified time.Time
+}
+
+func (v ResourceVersion) exists() bool {
+	return v.ETag != "" || !v.LastModified.IsZero()
+}
+
+// JSONEtag returns the etag EtagHandler generates for c.JSON(200, v).
+// Use it to declare the version of a resource returned as JSON, with the options
+// of the server (Config.EtagOptions) when it does not use the default sha1 strong etags.
+func JSONEtag(v any, opts ...EtagOption) (string, error) {
+	config := &etagConfig{hash: EtagHashSHA1}
+	for _, opt := range opts {
+		opt(config)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	h := config.hash()
+	h.Write(b)
+	return FormatEtag(len(b), h.Sum(nil), config.weak), nil
+}
+
+// PreconditionMiddleware responds with 428 Precondition Required
+// to PUT, PATCH and DELETE requests without If-Match or If-Unmodified-Since when required is true.
+// Handlers then call CheckPreconditions with the current version of the resource.
+func PreconditionMiddleware(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if required && isWriteMethod(c.Request.Method) &&
+			c.GetHeader(headers.IfMatch) == "" &&
+			c.GetHeader(headers.IfUnmodifiedSince) == "" {
+			shared.ErrorToHTTP(c, shared.NewError(shared.ErrPreconditionRequired, "",
+				"If-Match or If-Unmodified-Since header is required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckPreconditions evaluates the conditional headers of the request against
+// the current version of the resource, as described in RFC 9110 section 13.2.2.
+// It returns false when the request was aborted with 412 Precondition Failed
+// (or 304 Not Modified for GET and HEAD), the handler must then return.
+func CheckPreconditions(c *gin.Context, version ResourceVersion) bool {
+	req := c.Request
+
+	if ifMatch := req.Header.Get(headers.IfMatch); ifMatch != "" {
+		if !etagMatches(ifMatch, version, true) {
+			preconditionFailed(c, "If-Match")
+			return false
+		}
+	} else if since := req.Header.Get(headers.IfUnmodifiedSince); since != "" && !version.LastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && version.LastModified.Truncate(time.Second).After(t) {
+			preconditionFailed(c, "If-Unmodified-Since")
+			return false
+		}
+	}
+
+	if ifNoneMatch := req.Header.Get(headers.IfNoneMatch); ifNoneMatch != "" {
+		if etagMatches(ifNoneMatch, version, false) {
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				notModified(c, version)
+			} else {
+				preconditionFailed(c, "If-None-Match")
+			}
+			return false
+		}
+	} else if since := req.Header.Get(headers.IfModifiedSince); since != "" &&
+		(req.Method == http.MethodGet || req.Method == http.MethodHead) && !version.LastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && !version.LastModified.Truncate(time.Second).After(t) {
+			notModified(c, version)
+			return false
+		}
+	}
+
+	return true
+}
+
+func preconditionFailed(c *gin.Context, header string) {
+	shared.ErrorToHTTP(c, shared.NewError(shared.ErrPreconditionFailed, "", header+" does not match"))
+}
+
+func notModified(c *gin.Context, version ResourceVersion) {
+	if version.ETag != "" {
+		c.Header(headers.ETag, version.ETag)
+	}
+	if !version.LastModified.IsZero() {
+		c.Header(headers.LastModified, version.LastModified.UTC().Format(http.TimeFormat))
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+}
+
+// etagMatches compares the etags of an If-Match (strong) or If-None-Match (weak) header.
+func etagMatches(header string, version ResourceVersion, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return version.exists()
+	}
+	if version.ETag == "" {
+		return false
+	}
+	current, currentWeak := parseEtag(version.ETag)
+	if strong && currentWeak {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		opaque, weak := parseEtag(tag)
+		if strong && weak {
+			continue
+		}
+		if opaque == current {
+			return true
+		}
+	}
+	return false
+}
+
+func parseEtag(tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	opaque, weak := strings.CutPrefix(tag, "W/")
+	return opaque, weak
+}
+
+func isWriteMethod(method string) bool {
+	return method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete
+}
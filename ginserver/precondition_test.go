@@ -0,0 +1,83 @@
+package ginserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreconditions(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	current := item{Name: "foo"}
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	router := gin.New()
+	router.Use(ginserver.PreconditionMiddleware(true))
+	router.GET("/item", func(c *gin.Context) {
+		c.JSON(http.StatusOK, current)
+	})
+	router.PUT("/item", func(c *gin.Context) {
+		etag, err := ginserver.JSONEtag(current)
+		require.NoError(t, err)
+		if !ginserver.CheckPreconditions(c, ginserver.ResourceVersion{ETag: etag, LastModified: lastModified}) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	handler := ginserver.EtagHandler(router)
+
+	do := func(method string, h map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/item", nil)
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	etag := do(http.MethodGet, nil).Header().Get(headers.ETag)
+	require.NotEmpty(t, etag)
+
+	t.Run("missing precondition", func(t *testing.T) {
+		w := do(http.MethodPut, nil)
+		require.Equal(t, http.StatusPreconditionRequired, w.Code)
+		require.Equal(t, ginserver.ProblemContentType, w.Header().Get(headers.ContentType))
+	})
+
+	t.Run("matching etag", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, do(http.MethodPut, map[string]string{headers.IfMatch: etag}).Code)
+		require.Equal(t, http.StatusNoContent, do(http.MethodPut, map[string]string{headers.IfMatch: `"x", ` + etag}).Code)
+		require.Equal(t, http.StatusNoContent, do(http.MethodPut, map[string]string{headers.IfMatch: "*"}).Code)
+	})
+
+	t.Run("stale etag", func(t *testing.T) {
+		require.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, map[string]string{headers.IfMatch: `"1-abc"`}).Code)
+		// If-Match uses the strong comparison
+		require.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, map[string]string{headers.IfMatch: "W/" + etag}).Code)
+	})
+
+	t.Run("if unmodified since", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, do(http.MethodPut, map[string]string{
+			headers.IfUnmodifiedSince: lastModified.Format(http.TimeFormat),
+		}).Code)
+		require.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, map[string]string{
+			headers.IfUnmodifiedSince: lastModified.Add(-time.Hour).Format(http.TimeFormat),
+		}).Code)
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		require.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, map[string]string{
+			headers.IfMatch:     etag,
+			headers.IfNoneMatch: "*",
+		}).Code)
+	})
+}
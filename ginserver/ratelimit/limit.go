@@ -0,0 +1,171 @@
+// Package ratelimit limits the request rate of clients, see Middleware.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm of a Limit.
+type Algorithm string
+
+const (
+	// TokenBucket refills Requests tokens per Period up to Burst, every request takes a token.
+	TokenBucket Algorithm = "token-bucket"
+	// SlidingWindow allows Requests per Period, weighting the count of the previous
+	// window by how much of it overlaps the sliding window.
+	SlidingWindow Algorithm = "sliding-window"
+)
+
+// ParseAlgorithm parses token-bucket or sliding-window.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch Algorithm(strings.ToLower(name)) {
+	case "", TokenBucket:
+		return TokenBucket, nil
+	case SlidingWindow:
+		return SlidingWindow, nil
+	default:
+		return "", fmt.Errorf("unknown rate limit algorithm %q", name)
+	}
+}
+
+// Limit allows Requests per Period. A Limit without requests is unlimited.
+type Limit struct {
+	Requests  int
+	Period    time.Duration
+	Algorithm Algorithm
+	// size of the token bucket, defaults to Requests
+	Burst int
+}
+
+// ParseLimit parses requests per period, e.g. 100/1m, 10/s or 5000/h.
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected requests/period", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: invalid requests", s)
+	}
+	period = strings.TrimSpace(period)
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: invalid period", s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Unlimited reports whether the limit allows every request.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Policy formats the limit for the RateLimit-Policy header, e.g. 100;w=60.
+func (l Limit) Policy() string {
+	policy := fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Period.Seconds())))
+	if l.Algorithm != SlidingWindow && l.burst() != l.Requests {
+		policy += fmt.Sprintf(";burst=%d", l.burst())
+	}
+	return policy
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// State is the state of a key, stores keep it as is between calls to Take.
+// The zero value is the state of a key without requests.
+type State struct {
+	// token bucket: tokens left at Updated,
+	// sliding window: requests in the window starting at Updated
+	Value float64
+	// sliding window: requests in the previous window
+	Previous float64
+	Updated  time.Time
+}
+
+// Result of a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// time until the quota is fully restored
+	Reset time.Duration
+	// time until the next request is allowed, when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// Take takes a request at now from state and returns the new state.
+// Stores call it while holding the state of the key.
+func (l Limit) Take(state State, now time.Time) (State, Result) {
+	if l.Algorithm == SlidingWindow {
+		return l.slidingWindow(state, now)
+	}
+	return l.tokenBucket(state, now)
+}
+
+func (l Limit) tokenBucket(state State, now time.Time) (State, Result) {
+	capacity := float64(l.burst())
+	perSecond := float64(l.Requests) / l.Period.Seconds()
+
+	tokens := capacity
+	if !state.Updated.IsZero() {
+		tokens = math.Min(capacity, state.Value+now.Sub(state.Updated).Seconds()*perSecond)
+	}
+	result := Result{Limit: l.burst()}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = seconds((capacity - tokens) / perSecond)
+	return State{Value: tokens, Updated: now}, result
+}
+
+func (l Limit) slidingWindow(state State, now time.Time) (State, Result) {
+	start := now.Truncate(l.Period)
+	switch {
+	case state.Updated.Equal(start):
+	case state.Updated.Equal(start.Add(-l.Period)):
+		state = State{Previous: state.Value, Updated: start}
+	default:
+		state = State{Updated: start}
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - elapsed.Seconds()/l.Period.Seconds()
+	count := state.Previous*weight + state.Value
+	limit := float64(l.Requests)
+
+	result := Result{Limit: l.Requests, Reset: l.Period - elapsed}
+	if count+1 <= limit {
+		state.Value++
+		count++
+		result.Allowed = true
+	} else {
+		// wait until the previous window weighs little enough, or for the next window
+		result.RetryAfter = l.Period - elapsed
+		if state.Previous > 0 && state.Value+1 <= limit {
+			at := time.Duration((1 - (limit-1-state.Value)/state.Previous) * float64(l.Period))
+			result.RetryAfter = max(at-elapsed, time.Millisecond)
+		}
+	}
+	result.Remaining = max(int(limit-count), 0)
+	return state, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Rate limit headers, see https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyFunc returns the key a request is counted under.
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests by client IP, see gin.Context.ClientIP.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByAPIKey counts requests by the ID of the authenticated API key (shared.APIKeyContextKey),
+// other requests are counted by IP. Use it after the API key middleware like KeyBySubject:
+// unverified keys would let clients pick a new key, and a new limit, for each request.
+func KeyByAPIKey(c *gin.Context) string {
+	v, _ := c.Get(shared.APIKeyContextKey)
+	if key, ok := v.(*shared.APIKey); ok && key.ID != "" {
+		return "api_key:" + key.ID
+	}
+	return KeyByIP(c)
+}
+
+// KeyBySubject counts requests by authenticated subject (shared.SubjectContextKey),
+// anonymous requests are counted by IP. Use it after the authentication middleware, e.g. with
+// Middleware on a group of authenticated routes: the limiter of ginserver.Config runs before authentication.
+func KeyBySubject(c *gin.Context) string {
+	if subject := c.GetString(shared.SubjectContextKey); subject != "" {
+		return "subject:" + subject
+	}
+	return KeyByIP(c)
+}
+
+// KeyByRoute counts requests by route (c.FullPath()), all clients share the limit.
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath()
+}
+
+// Keys counts requests by the combination of keys, e.g. Keys(KeyBySubject, KeyByRoute).
+func Keys(keys ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key(c)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// KeyByName returns the KeyFunc named ip, api-key, subject or route.
+func KeyByName(name string) (KeyFunc, error) {
+	switch strings.ToLower(name) {
+	case "", "ip":
+		return KeyByIP, nil
+	case "api-key":
+		return KeyByAPIKey, nil
+	case "subject":
+		return KeyBySubject, nil
+	case "route":
+		return KeyByRoute, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", name)
+	}
+}
+
+type config struct {
+	store  Store
+	key    KeyFunc
+	routes map[string]Limit
+}
+
+// Option configures Middleware.
+type Option func(*config)
+
+// WithStore sets the store of the states, defaults to a new MemoryStore.
+func WithStore(store Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithKey sets the key requests are counted under, defaults to KeyByIP.
+func WithKey(key KeyFunc) Option {
+	return func(c *config) {
+		c.key = key
+	}
+}
+
+// WithRoute overrides the limit of a route, either "/path/:id" (c.FullPath())
+// or "METHOD /path/:id". Requests to the route are counted separately.
+// An empty Limit disables rate limiting on the route.
+func WithRoute(route string, limit Limit) Option {
+	return func(c *config) {
+		c.routes[route] = limit
+	}
+}
+
+// ParseRoutes parses route overrides for WithRoute, e.g. "POST /login=5/1m".
+func ParseRoutes(routes []string) ([]Option, error) {
+	opts := make([]Option, 0, len(routes))
+	for _, route := range routes {
+		i := strings.LastIndex(route, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid rate limit route %q: expected route=limit", route)
+		}
+		limit, err := ParseLimit(route[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRoute(strings.TrimSpace(route[:i]), limit))
+	}
+	return opts, nil
+}
+
+// Middleware limits the requests of clients to limit. It sets the RateLimit-* headers
+// and aborts requests over the limit with shared.ErrRateLimited (429) and Retry-After.
+// Requests are allowed when the store fails.
+func Middleware(limit Limit, opts ...Option) gin.HandlerFunc {
+	config := &config{key: KeyByIP, routes: map[string]Limit{}}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.store == nil {
+		config.store = NewMemoryStore()
+	}
+
+	return func(c *gin.Context) {
+		limit, prefix := limit, ""
+		for _, route := range []string{c.Request.Method + " " + c.FullPath(), c.FullPath()} {
+			if l, ok := config.routes[route]; ok {
+				limit, prefix = inherit(l, limit), route+"|"
+				break
+			}
+		}
+		if limit.Unlimited() {
+			c.Next()
+			return
+		}
+
+		result, err := config.store.Take(c.Request.Context(), prefix+config.key(c), limit)
+		if err != nil {
+			zap.L().Error("rate limit store failed", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set(HeaderLimit, strconv.Itoa(result.Limit))
+		header.Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+		header.Set(HeaderReset, formatSeconds(result.Reset))
+		header.Set(HeaderPolicy, limit.Policy())
+		if !result.Allowed {
+			header.Set(HeaderRetryAfter, formatSeconds(result.RetryAfter))
+			shared.ErrorToHTTP(c, shared.ErrRateLimited.Wrap(errors.New(http.StatusText(http.StatusTooManyRequests))))
+			return
+		}
+		c.Next()
+	}
+}
+
+// inherit fills the algorithm of a route limit from the default limit.
+func inherit(route, limit Limit) Limit {
+	if route.Algorithm == "" {
+		route.Algorithm = limit.Algorithm
+	}
+	return route
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
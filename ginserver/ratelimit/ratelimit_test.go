@@ -0,0 +1,204 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver/ratelimit"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("100/1m")
+	require.NoError(t, err)
+	require.Equal(t, ratelimit.Limit{Requests: 100, Period: time.Minute}, limit)
+
+	limit, err = ratelimit.ParseLimit("10/s")
+	require.NoError(t, err)
+	require.Equal(t, ratelimit.Limit{Requests: 10, Period: time.Second}, limit)
+
+	for _, s := range []string{"100", "x/1m", "10/0s", "10/week"} {
+		_, err := ratelimit.ParseLimit(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Second, Burst: 3}
+	now := time.Now()
+	var state ratelimit.State
+	var result ratelimit.Result
+
+	for i := range 3 {
+		state, result = limit.Take(state, now)
+		require.True(t, result.Allowed)
+		require.Equal(t, 2-i, result.Remaining)
+	}
+	state, result = limit.Take(state, now)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	require.Equal(t, 1500*time.Millisecond, result.Reset)
+
+	// refilled 2 tokens per second
+	_, result = limit.Take(state, now.Add(500*time.Millisecond))
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 4, Period: time.Minute, Algorithm: ratelimit.SlidingWindow}
+	start := time.Now().Truncate(time.Minute)
+	var state ratelimit.State
+	var result ratelimit.Result
+
+	for range 4 {
+		state, result = limit.Take(state, start.Add(30*time.Second))
+		require.True(t, result.Allowed)
+	}
+	state, result = limit.Take(state, start.Add(30*time.Second))
+	require.False(t, result.Allowed)
+	require.Equal(t, 30*time.Second, result.RetryAfter)
+
+	// 4 requests in the previous window, weighted 5/6
+	state, result = limit.Take(state, start.Add(70*time.Second))
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	// allowed once the weight drops to 3/4
+	require.Equal(t, 5*time.Second, result.RetryAfter)
+
+	_, result = limit.Take(state, start.Add(90*time.Second))
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+
+	// windows older than the previous one are forgotten
+	_, result = limit.Take(state, start.Add(5*time.Minute))
+	require.True(t, result.Allowed)
+	require.Equal(t, 3, result.Remaining)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store down")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(opts ...ratelimit.Option) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if subject := c.GetHeader("X-Subject"); subject != "" {
+				c.Set(shared.SubjectContextKey, subject)
+			}
+			if id := c.GetHeader("X-Key-ID"); id != "" {
+				c.Set(shared.APIKeyContextKey, &shared.APIKey{ID: id})
+			}
+		})
+		router.Use(ratelimit.Middleware(ratelimit.Limit{Requests: 2, Period: time.Minute}, opts...))
+		for _, path := range []string{"/a", "/login", "/health"} {
+			router.GET(path, func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+		}
+		return router
+	}
+	get := func(router *gin.Engine, path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("429", func(t *testing.T) {
+		router := newRouter()
+		w := get(router, "/a")
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.Equal(t, "2", w.Header().Get(ratelimit.HeaderLimit))
+		require.Equal(t, "1", w.Header().Get(ratelimit.HeaderRemaining))
+		require.Equal(t, "30", w.Header().Get(ratelimit.HeaderReset))
+		require.Equal(t, "2;w=60", w.Header().Get(ratelimit.HeaderPolicy))
+
+		get(router, "/a")
+		w = get(router, "/a")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "30", w.Header().Get(ratelimit.HeaderRetryAfter))
+		require.Equal(t, "0", w.Header().Get(ratelimit.HeaderRemaining))
+		require.Equal(t, shared.ProblemContentType, w.Header().Get("Content-Type"))
+		require.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		router := newRouter(ratelimit.WithKey(ratelimit.KeyBySubject))
+		for range 2 {
+			require.Equal(t, http.StatusNoContent, get(router, "/a", "X-Subject", "alice").Code)
+		}
+		require.Equal(t, http.StatusTooManyRequests, get(router, "/a", "X-Subject", "alice").Code)
+		require.Equal(t, http.StatusNoContent, get(router, "/a", "X-Subject", "bob").Code)
+
+		router = newRouter(ratelimit.WithKey(ratelimit.KeyByAPIKey))
+		for range 2 {
+			require.Equal(t, http.StatusNoContent, get(router, "/a", "X-Key-ID", "k1").Code)
+		}
+		require.Equal(t, http.StatusTooManyRequests, get(router, "/a", "X-Key-ID", "k1").Code)
+		require.Equal(t, http.StatusNoContent, get(router, "/a", "X-Key-ID", "k2").Code)
+
+		// unauthenticated keys are counted by IP
+		router = newRouter(ratelimit.WithKey(ratelimit.KeyByAPIKey))
+		for _, key := range []string{"random-1", "random-2"} {
+			require.Equal(t, http.StatusNoContent, get(router, "/a", "X-API-Key", key).Code)
+		}
+		require.Equal(t, http.StatusTooManyRequests, get(router, "/a", "X-API-Key", "random-3").Code)
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		opts, err := ratelimit.ParseRoutes([]string{"GET /login=1/1m", "/health=0/1s"})
+		require.NoError(t, err)
+		router := newRouter(opts...)
+
+		require.Equal(t, http.StatusNoContent, get(router, "/login").Code)
+		require.Equal(t, http.StatusTooManyRequests, get(router, "/login").Code)
+		// counted separately from the default limit
+		require.Equal(t, http.StatusNoContent, get(router, "/a").Code)
+
+		for range 5 {
+			w := get(router, "/health")
+			require.Equal(t, http.StatusNoContent, w.Code)
+			require.Empty(t, w.Header().Get(ratelimit.HeaderLimit))
+		}
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		router := newRouter(ratelimit.WithStore(failingStore{}))
+		for range 3 {
+			require.Equal(t, http.StatusNoContent, get(router, "/a").Code)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	result, err := store.Take(context.Background(), "a", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	result, err = store.Take(context.Background(), "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 1, store.Len())
+
+	store = ratelimit.NewMemoryStore(ratelimit.WithMaxKeys(2))
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err = store.Take(context.Background(), key, limit)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, store.Len())
+}
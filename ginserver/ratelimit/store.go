@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the state of rate limited keys.
+// Implementations take the request with Limit.Take while holding the state of the key,
+// e.g. in a transaction locking its row.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// MemoryStore keeps states in memory, limits are then per process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	maxKeys   int
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// MemoryStoreOption configures NewMemoryStore.
+type MemoryStoreOption func(*MemoryStore)
+
+// WithMaxKeys sets the number of keys kept in memory, defaults to 100000.
+// Keys are evicted once it is reached, their clients then start with a new limit.
+func WithMaxKeys(n int) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		s.maxKeys = n
+	}
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	s := &MemoryStore{entries: map[string]*memoryEntry{}, maxKeys: 100000, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// sweepInterval is how often expired keys are removed.
+const sweepInterval = time.Minute
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		s.evict()
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	var result Result
+	entry.state, result = limit.Take(entry.state, now)
+	// the state of a key without requests is the zero state
+	entry.expires = now.Add(result.Reset + limit.Period)
+	return result, nil
+}
+
+// Len returns the number of keys in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// evict removes keys until there is room for a new one, in the random order of the map.
+func (s *MemoryStore) evict() {
+	if s.maxKeys <= 0 {
+		return
+	}
+	for key := range s.entries {
+		if len(s.entries) < s.maxKeys {
+			return
+		}
+		delete(s.entries, key)
+	}
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,101 @@
+package ginserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// PanicReporter receives recovered panics, e.g. to forward them to Sentry.
+type PanicReporter func(c *gin.Context, recovered any, stack []byte)
+
+//nolint:gochecknoglobals // metrics can only be registered once.
+var panicsTotal = sync.OnceValue(func() *prometheus.CounterVec {
+	return promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_panics_total",
+		Help: "Number of panics recovered in HTTP handlers.",
+	}, []string{"http_route", "http_method"})
+})
+
+// RecoveryMiddleware recovers panics in handlers.
+// The panic is logged, recorded on the span and counted in http_panics_total,
+// then the request is aborted with the problem details of shared.ErrUnexpected.
+// reporter is optional.
+func RecoveryMiddleware(logger *otelzap.Logger, reporter PanicReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			stack := debug.Stack()
+			ctx := c.Request.Context()
+
+			// the client is gone, there is nobody to respond to
+			if isBrokenPipe(recovered) {
+				logger.Ctx(ctx).Warn("broken connection",
+					zap.Any("error", recovered),
+					zap.String("path", c.Request.URL.Path))
+				c.Abort()
+				return
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+
+			span := trace.SpanFromContext(ctx)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic: "+err.Error())
+
+			panicsTotal().WithLabelValues(c.FullPath(), c.Request.Method).Inc()
+
+			logger.Ctx(ctx).Error("panic recovered",
+				zap.Any("error", recovered),
+				zap.String("path", c.Request.URL.Path),
+				zap.ByteString("stack", stack))
+
+			if reporter != nil {
+				reporter(c, recovered, stack)
+			}
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			shared.WriteProblem(c, shared.HTTPErrors().Problem(c, shared.ErrUnexpected.Wrap(err)))
+		}()
+		c.Next()
+	}
+}
+
+func isBrokenPipe(recovered any) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,42 @@
+package ginserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var reported any
+	r := gin.New()
+	r.Use(ginserver.RecoveryMiddleware(otelzap.New(zap.NewNop()),
+		func(_ *gin.Context, recovered any, stack []byte) {
+			reported = recovered
+			require.NotEmpty(t, stack)
+		}))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, ginserver.ProblemContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, "boom", reported)
+
+	p := ginserver.Problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, "unexpected", p.Code)
+	require.Equal(t, "req-1", p.RequestID)
+	require.Empty(t, p.Detail)
+}
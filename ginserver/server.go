@@ -0,0 +1,435 @@
+package ginserver
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver/openapi"
+	"github.com/Lysoul/gocommon/ginserver/ratelimit"
+	"github.com/Lysoul/gocommon/monitoring"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
+)
+
+// servers created by InitGin, by router, for Run. Servers are removed on Shutdown.
+//
+//nolint:gochecknoglobals // sync.Map is safe for concurrent use by multiple goroutines.
+var servers sync.Map
+
+// Option configures a Server.
+type Option func(*options)
+
+type options struct {
+	logger         *otelzap.Logger
+	panicReporter  PanicReporter
+	rateLimitStore ratelimit.Store
+	openAPISpec    *OpenAPISpec
+}
+
+// WithLogger sets the logger of the server, defaults to the global zap logger.
+func WithLogger(logger *otelzap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithPanicReporter calls reporter for every panic recovered in handlers.
+func WithPanicReporter(reporter PanicReporter) Option {
+	return func(o *options) {
+		o.panicReporter = reporter
+	}
+}
+
+// WithRateLimitStore sets the store of the rate limiter configured by Config.RateLimit,
+// defaults to a ratelimit.MemoryStore.
+func WithRateLimitStore(store ratelimit.Store) Option {
+	return func(o *options) {
+		o.rateLimitStore = store
+	}
+}
+
+// WithOpenAPISpec validates the requests against spec, e.g. loaded from an embed.FS
+// with LoadOpenAPISpec. It takes precedence over Config.OpenAPISpec.
+func WithOpenAPISpec(spec *OpenAPISpec) Option {
+	return func(o *options) {
+		o.openAPISpec = spec
+	}
+}
+
+// Server owns a gin router, its middleware chain and the http.Server serving it.
+// Several servers can run in the same process, e.g. a public and an admin API.
+//
+// Note that gin's mode is global, the mode of the last server created wins.
+type Server struct {
+	config Config
+	logger *otelzap.Logger
+	router *gin.Engine
+	api    *API
+
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+	ready    chan struct{}
+	done     chan struct{}
+	serveErr error
+	draining atomic.Bool
+}
+
+// New creates a server and its router with the default middlewares.
+// It fails when the config is invalid, e.g. the rate limit or the OpenAPI document.
+func New(config Config, opts ...Option) (*Server, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = otelzap.New(zap.L())
+	}
+	logger := o.logger
+
+	if config.Mode == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	} else {
+		gin.SetMode(gin.DebugMode)
+	}
+	router := gin.New()
+	router.ContextWithFallback = true
+
+	// info.version is required by OpenAPI
+	version := cmp.Or(config.OpenAPIVersion, "1.0.0")
+	s := &Server{
+		config: config,
+		logger: logger,
+		router: router,
+		api:    NewAPI(openapi.Info{Title: config.ServiceName, Version: version}),
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if config.EnableCORS {
+		logger.Info("CORS enabled")
+		router.Use(CORSMiddleware(config))
+	} else {
+		logger.Info("CORS disabled")
+	}
+
+	router.Use(RequestID())
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		router.Use(ClientCertificate())
+	}
+	router.Use(otelgin.Middleware(config.ServiceName))
+	router.Use(
+		GinZapSetup(logger, config),
+		// Logs all panic to error log, records them on the span and responds with problem details
+		RecoveryMiddleware(logger, o.panicReporter),
+	)
+
+	router.Use(MetricsMiddleware(
+		config.MetricsDurationBuckets,
+		config.MetricsSizeBuckets,
+	))
+
+	if config.RateLimit != "" {
+		rateLimit, err := rateLimitMiddleware(config, o.rateLimitStore)
+		if err != nil {
+			return nil, fmt.Errorf("ginserver: invalid rate limit: %w", err)
+		}
+		router.Use(rateLimit)
+	}
+
+	if len(config.Languages) > 0 {
+		router.Use(LanguageMiddleware(config.Languages))
+	}
+
+	router.Use(BindMiddleware(
+		WithMaxBodySize(config.MaxBodySize),
+		WithDisallowUnknownFields(config.DisallowUnknownFields),
+	))
+
+	spec := o.openAPISpec
+	if spec == nil && config.OpenAPISpec != "" {
+		var err error
+		spec, err = LoadOpenAPISpec(os.DirFS(filepath.Dir(config.OpenAPISpec)), filepath.Base(config.OpenAPISpec))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if spec != nil {
+		router.Use(OpenAPIValidationMiddleware(spec, WithResponseValidation(config.OpenAPIValidateResponses)))
+	}
+
+	if config.RequirePreconditions {
+		if config.EnableEtag && config.EtagWeak {
+			// If-Match would never match, writes would be impossible
+			return nil, errors.New("ginserver: RequirePreconditions requires strong etags, disable EtagWeak")
+		}
+		router.Use(PreconditionMiddleware(true))
+	}
+
+	if config.CacheContolDefault != "" {
+		router.Use(CacheControlMiddleware(config.CacheContolDefault))
+	}
+
+	if config.HealthPath != "" {
+		router.GET(config.HealthPath, s.healthHandler())
+	}
+
+	if config.OpenAPIPath != "" {
+		router.GET(config.OpenAPIPath+".json", s.api.Handler(false))
+		router.GET(config.OpenAPIPath+".yaml", s.api.Handler(true))
+	}
+
+	return s, nil
+}
+
+// Router returns the router to register routes on.
+func (s *Server) Router() *gin.Engine {
+	return s.router
+}
+
+// API returns the documentation of the routes of the server, see Config.OpenAPIPath.
+func (s *Server) API() *API {
+	return s.api
+}
+
+// Routes returns the root routes of the server, documented in its API.
+func (s *Server) Routes() *Routes {
+	return s.api.Routes(&s.router.RouterGroup)
+}
+
+// Config returns the config of the server.
+func (s *Server) Config() Config {
+	return s.config
+}
+
+// Handler returns the router wrapped with the http level handlers (etag and compression).
+func (s *Server) Handler() http.Handler {
+	var handler http.Handler
+	if s.config.EnableEtag {
+		handler = EtagHandler(s.router, s.config.EtagOptions()...)
+	} else {
+		handler = s.router
+	}
+	if len(s.config.Compression) > 0 {
+		handler = CompressionHandler(handler,
+			WithCompressionEncodings(s.config.Compression...),
+			WithCompressionMinSize(s.config.CompressionMinSize),
+			WithCompressionContentTypes(s.config.CompressionContentTypes...),
+		)
+	}
+	return handler
+}
+
+// Start binds the listener and serves in the background.
+// Bind errors (e.g. port in use) are returned, serve errors are returned by Wait.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		return errors.New("ginserver: server already started")
+	}
+
+	readHeaderTimeout := s.config.ReadHeaderTimeout
+	if readHeaderTimeout == 0 {
+		readHeaderTimeout = 10 * time.Second
+	}
+	network, address, err := s.config.listenAddr()
+	if err != nil {
+		return fmt.Errorf("ginserver: %w", err)
+	}
+	server := &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		Protocols:         s.config.protocols(),
+		ReadTimeout:       s.config.ReadTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      s.config.WriteTimeout,
+		IdleTimeout:       s.config.IdleTimeout,
+		MaxHeaderBytes:    s.config.MaxHeaderBytes,
+	}
+
+	var certs *certReloader
+	if s.config.TLSCertFile != "" || s.config.TLSKeyFile != "" {
+		clientAuth, err := ParseClientAuth(s.config.TLSClientAuth)
+		if err != nil {
+			return fmt.Errorf("ginserver: %w", err)
+		}
+		// without client CAs, client certificates would be verified against the system roots
+		if clientAuth >= tls.VerifyClientCertIfGiven && s.config.TLSClientCAFile == "" {
+			return fmt.Errorf("ginserver: client auth %q requires a client CA file", s.config.TLSClientAuth)
+		}
+		certs, err = newCertReloader(s.config.TLSCertFile, s.config.TLSKeyFile, s.config.TLSClientCAFile)
+		if err != nil {
+			return fmt.Errorf("ginserver: %w", err)
+		}
+		server.TLSConfig = certs.tlsConfig(clientAuth)
+	}
+
+	listener, err := listen(network, address, s.config.SocketMode)
+	if err != nil {
+		return fmt.Errorf("ginserver: listen on %s %s: %w", network, address, err)
+	}
+	s.server = server
+	s.listener = listener
+	s.logger.Info("HTTP server listening",
+		zap.String("network", network),
+		zap.String("addr", listener.Addr().String()),
+		zap.Bool("tls", certs != nil),
+		zap.Bool("h2c", s.config.H2C))
+	close(s.ready)
+
+	if certs != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-s.done
+			cancel()
+		}()
+		go certs.watch(ctx, s.config.TLSReloadInterval, s.logger)
+	}
+
+	go func() {
+		defer close(s.done)
+		var err error
+		if certs != nil {
+			// certificates come from TLSConfig.GetCertificate
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("HTTP server failed", zap.Error(err))
+			s.mu.Lock()
+			s.serveErr = err
+			s.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// Ready is closed once the server listens.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr returns the address the server listens on, nil before it is ready.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Draining reports whether the server is shutting down.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// HealthCheck fails while the server is draining, register it with monitoring.AddCheck
+// when the health check is not served by this server.
+func (s *Server) HealthCheck(context.Context) error {
+	if s.Draining() {
+		return errors.New("ginserver: server is draining")
+	}
+	return nil
+}
+
+func (s *Server) healthHandler() gin.HandlerFunc {
+	health := sync.OnceValue(func() http.Handler {
+		return monitoring.HealthHandler(monitoring.HealthConfig{ServiceName: s.config.ServiceName})
+	})
+	return func(c *gin.Context) {
+		if s.Draining() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
+		health().ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// Run starts the server (see Start) and returns the http.Server and a function to shut it down.
+func (s *Server) Run() (*http.Server, func(context.Context), error) {
+	if err := s.Start(); err != nil {
+		return nil, nil, err
+	}
+	return s.server, func(ctx context.Context) {
+		if err := s.Shutdown(ctx); err != nil {
+			s.logger.Error("Server forced to shutdown:", zap.Error(err))
+		} else {
+			s.logger.Info("HTTP server has shut down")
+		}
+	}, nil
+}
+
+// ListenAndServe starts the server and blocks until ctx is done, SIGTERM or SIGINT
+// is received or serving fails. It then drains the server, see Drain.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+		s.logger.Info("HTTP server stopping")
+	case <-s.done:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.serveErr
+	}
+	return s.Drain(context.WithoutCancel(ctx))
+}
+
+// Drain reports not ready during Config.DrainPeriod, then shuts down the server,
+// waiting at most Config.ShutdownTimeout for in flight requests.
+func (s *Server) Drain(ctx context.Context) error {
+	s.draining.Store(true)
+	if s.config.DrainPeriod > 0 {
+		s.logger.Info("HTTP server draining", zap.Duration("period", s.config.DrainPeriod))
+		select {
+		case <-time.After(s.config.DrainPeriod):
+		case <-ctx.Done():
+		}
+	}
+
+	if s.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		return err
+	}
+	s.logger.Info("HTTP server has shut down")
+	return nil
+}
+
+// Shutdown gracefully shuts down the server, see http.Server.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+	servers.Delete(s.router)
+	s.mu.Lock()
+	server := s.server
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
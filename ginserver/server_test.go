@@ -0,0 +1,199 @@
+package ginserver_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+func TestMultipleServers(t *testing.T) {
+	logger := ginserver.WithLogger(otelzap.New(zap.NewNop()))
+	public, err := ginserver.New(ginserver.Config{Mode: "dev", EnableEtag: true}, logger)
+	require.NoError(t, err)
+	admin, err := ginserver.New(ginserver.Config{Mode: "dev", CacheContolDefault: "private"}, logger)
+	require.NoError(t, err)
+
+	for name, s := range map[string]*ginserver.Server{"public": public, "admin": admin} {
+		s.Router().GET("/name", func(c *gin.Context) {
+			c.String(http.StatusOK, name)
+		})
+	}
+
+	get := func(s *ginserver.Server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/name", nil))
+		return w
+	}
+
+	t.Run("public", func(t *testing.T) {
+		t.Parallel()
+		w := get(public)
+		require.Equal(t, "public", w.Body.String())
+		require.NotEmpty(t, w.Header().Get(headers.ETag))
+		require.Empty(t, w.Header().Get(headers.CacheControl))
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		w := get(admin)
+		require.Equal(t, "admin", w.Body.String())
+		require.Empty(t, w.Header().Get(headers.ETag))
+		require.Equal(t, "private", w.Header().Get(headers.CacheControl))
+	})
+}
+
+func TestServerLifecycle(t *testing.T) {
+	logger := ginserver.WithLogger(otelzap.New(zap.NewNop()))
+	s, err := ginserver.New(ginserver.Config{
+		Mode:        "dev",
+		HealthPath:  "/health",
+		DrainPeriod: 200 * time.Millisecond,
+	}, logger)
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+
+	select {
+	case <-s.Ready():
+	default:
+		t.Fatal("server is not ready after Start")
+	}
+	url := "http://" + s.Addr().String() + "/health"
+
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	t.Run("bind errors are returned", func(t *testing.T) {
+		_, port, err := net.SplitHostPort(s.Addr().String())
+		require.NoError(t, err)
+		p, err := strconv.Atoi(port)
+		require.NoError(t, err)
+
+		other, err := ginserver.New(ginserver.Config{Mode: "dev", Port: p}, logger)
+		require.NoError(t, err)
+		_, _, err = other.Run()
+		require.Error(t, err)
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		drained := make(chan error)
+		go func() {
+			drained <- s.Drain(context.Background())
+		}()
+
+		require.Eventually(t, s.Draining, time.Second, 10*time.Millisecond)
+		res, err := http.Get(url)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+		require.NoError(t, <-drained)
+		_, err = http.Get(url)
+		require.Error(t, err)
+	})
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	s, err := ginserver.New(ginserver.Config{
+		Mode:            "dev",
+		RateLimit:       "1/1m",
+		RateLimitKey:    []string{"ip", "route"},
+		RateLimitRoutes: []string{"/unlimited=0/1s"},
+	}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+	for _, path := range []string{"/limited", "/unlimited"} {
+		s.Router().GET(path, func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	}
+
+	codes := func(path string) []int {
+		var codes []int
+		for range 2 {
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			codes = append(codes, w.Code)
+		}
+		return codes
+	}
+	require.Equal(t, []int{http.StatusNoContent, http.StatusTooManyRequests}, codes("/limited"))
+	require.Equal(t, []int{http.StatusNoContent, http.StatusNoContent}, codes("/unlimited"))
+}
+
+func TestInvalidRateLimitConfig(t *testing.T) {
+	for _, config := range []ginserver.Config{
+		{RateLimit: "often"},
+		{RateLimit: "1/1m", RateLimitKey: []string{"subject"}},
+		{RateLimit: "1/1m", RateLimitKey: []string{"ip", "api-key"}},
+	} {
+		_, err := ginserver.New(config, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+		require.ErrorContains(t, err, "invalid rate limit")
+	}
+}
+
+func TestRun(t *testing.T) {
+	router, _ := ginserver.InitGin(ginserver.Config{Mode: "dev"}, otelzap.New(zap.NewNop()))
+	_, shutdown, err := ginserver.Run(router)
+	require.NoError(t, err)
+	shutdown(context.Background())
+
+	_, _, err = ginserver.Run(router)
+	require.Error(t, err, "servers are forgotten once shut down")
+	_, _, err = ginserver.Run(gin.New())
+	require.Error(t, err, "other routers have no config")
+}
+
+func TestInitGinPanicsOnInvalidConfig(t *testing.T) {
+	require.Panics(t, func() {
+		ginserver.InitGin(ginserver.Config{RateLimit: "often"}, otelzap.New(zap.NewNop()))
+	})
+}
+
+func TestPreconditionsWithServerEtagOptions(t *testing.T) {
+	config := ginserver.Config{RequirePreconditions: true, EnableEtag: true, EtagHash: "xxhash"}
+	server, err := ginserver.New(config, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+
+	current := gin.H{"name": "foo"}
+	server.Router().GET("/item", func(c *gin.Context) {
+		c.JSON(http.StatusOK, current)
+	})
+	server.Router().PUT("/item", func(c *gin.Context) {
+		etag, err := ginserver.JSONEtag(current, server.Config().EtagOptions()...)
+		require.NoError(t, err)
+		if ginserver.CheckPreconditions(c, ginserver.ResourceVersion{ETag: etag}) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item", nil))
+	etag := w.Header().Get(headers.ETag)
+	defaultEtag, err := ginserver.JSONEtag(current)
+	require.NoError(t, err)
+	require.NotEqual(t, defaultEtag, etag)
+
+	req := httptest.NewRequest(http.MethodPut, "/item", nil)
+	req.Header.Set(headers.IfMatch, etag)
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestPreconditionsRequireStrongEtags(t *testing.T) {
+	_, err := ginserver.New(ginserver.Config{RequirePreconditions: true, EnableEtag: true, EtagWeak: true},
+		ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.ErrorContains(t, err, "requires strong etags")
+}
@@ -0,0 +1,179 @@
+package ginserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+// ClientCertSubjectKey is the gin context key of the subject of the verified client certificate.
+const ClientCertSubjectKey = "client_cert_subject"
+
+// ParseClientAuth parses none, request, require, verify-if-given or require-and-verify.
+func ParseClientAuth(name string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify-if-given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require-and-verify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown client auth %q", name)
+	}
+}
+
+// ClientCertificate stores the subject of the verified client certificate in the gin context, see ClientCertSubject.
+func ClientCertificate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subject := clientCertSubject(c); subject != "" {
+			c.Set(ClientCertSubjectKey, subject)
+		}
+		c.Next()
+	}
+}
+
+// ClientCertSubject returns the subject of the verified client certificate, empty without mTLS.
+func ClientCertSubject(c *gin.Context) string {
+	if subject := c.GetString(ClientCertSubjectKey); subject != "" {
+		return subject
+	}
+	return clientCertSubject(c)
+}
+
+// clientCertSubject returns the subject of the verified client certificate: the certificates
+// of the request and require client auths are not verified, their subject is chosen by the client.
+func clientCertSubject(c *gin.Context) string {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return c.Request.TLS.VerifiedChains[0][0].Subject.String()
+}
+
+// certReloader serves the certificate and client CAs of files,
+// reloading them when their content changes (e.g. cert-manager rotations).
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	contents  [][]byte
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the files if they changed, and reports whether they did.
+func (r *certReloader) reload() (bool, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return false, fmt.Errorf("tls: %w", err)
+		}
+		contents[i] = b
+	}
+
+	r.mu.RLock()
+	changed := !slices.EqualFunc(r.contents, contents, bytes.Equal)
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return false, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(contents[2]) {
+			return false, errors.New("tls: no certificate found in " + r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.contents = contents
+	r.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the files every interval until ctx is done.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration, logger *otelzap.Logger) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			switch {
+			case err != nil:
+				logger.Error("failed to reload TLS certificates", zap.Error(err))
+			case changed:
+				logger.Info("reloaded TLS certificates")
+			}
+		}
+	}
+}
+
+func (r *certReloader) tlsConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// http.Server only adds ALPN to its own copy of the config, not to the configs of clients
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if clientAuth == tls.NoClientCert {
+		return config
+	}
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := config.Clone()
+		c.GetConfigForClient = nil
+		c.ClientAuth = clientAuth
+		r.mu.RLock()
+		c.ClientCAs = r.clientCAs
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return config
+}
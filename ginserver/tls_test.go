@@ -0,0 +1,229 @@
+package ginserver_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/ginserver"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"gocommon"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	cert, err := tls.X509KeyPair(c.pem, c.keyPEM(t))
+	require.NoError(t, err)
+	return cert
+}
+
+func writeTestCert(t *testing.T, dir string, c *testCert) (string, string) {
+	t.Helper()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, c.pem, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, c.keyPEM(t), 0o600))
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test ca", nil)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "server", ca))
+	client := newTestCert(t, "client", ca)
+
+	s, err := ginserver.New(ginserver.Config{
+		Mode:              "dev",
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+		TLSClientCAFile:   caFile,
+		TLSClientAuth:     "require-and-verify",
+		TLSReloadInterval: 50 * time.Millisecond,
+	}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+	s.Router().GET("/subject", func(c *gin.Context) {
+		c.String(http.StatusOK, ginserver.ClientCertSubject(c))
+	})
+	require.NoError(t, s.Start())
+	t.Cleanup(func() {
+		require.NoError(t, s.Shutdown(context.Background()))
+	})
+	_, port, err := net.SplitHostPort(s.Addr().String())
+	require.NoError(t, err)
+	addr := net.JoinHostPort("localhost", port)
+	url := "https://" + addr + "/subject"
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12},
+		}}
+	}
+
+	t.Run("client certificate", func(t *testing.T) {
+		res, err := newClient(client.tlsCertificate(t)).Get(url)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, "CN=client,O=gocommon", string(body))
+	})
+
+	t.Run("http2", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{client.tlsCertificate(t)},
+			NextProtos:   []string{"h2", "http/1.1"},
+			MinVersion:   tls.VersionTLS12,
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, "h2", conn.ConnectionState().NegotiatedProtocol)
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		_, err := newClient().Get(url)
+		require.Error(t, err)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		rotated := newTestCert(t, "rotated server", ca)
+		writeTestCert(t, dir, rotated)
+
+		require.Eventually(t, func() bool {
+			conn, err := tls.Dial("tcp", addr, &tls.Config{
+				RootCAs:      roots,
+				Certificates: []tls.Certificate{client.tlsCertificate(t)},
+				MinVersion:   tls.VersionTLS12,
+			})
+			if err != nil {
+				return false
+			}
+			defer conn.Close()
+			return conn.ConnectionState().PeerCertificates[0].Subject.CommonName == "rotated server"
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+}
+
+func TestParseClientAuth(t *testing.T) {
+	auth, err := ginserver.ParseClientAuth("verify-if-given")
+	require.NoError(t, err)
+	require.Equal(t, tls.VerifyClientCertIfGiven, auth)
+
+	_, err = ginserver.ParseClientAuth("always")
+	require.Error(t, err)
+}
+
+func TestUnverifiedClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test ca", nil)
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "server", ca))
+
+	s, err := ginserver.New(ginserver.Config{
+		Mode:          "dev",
+		TLSCertFile:   certFile,
+		TLSKeyFile:    keyFile,
+		TLSClientAuth: "require",
+	}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+	require.NoError(t, err)
+	s.Router().GET("/subject", func(c *gin.Context) {
+		c.String(http.StatusOK, ginserver.ClientCertSubject(c))
+	})
+	require.NoError(t, s.Start())
+	t.Cleanup(func() {
+		require.NoError(t, s.Shutdown(context.Background()))
+	})
+	_, port, err := net.SplitHostPort(s.Addr().String())
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	forged := newTestCert(t, "admin", nil)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs: roots, Certificates: []tls.Certificate{forged.tlsCertificate(t)}, MinVersion: tls.VersionTLS12,
+	}}}
+	res, err := client.Get("https://" + net.JoinHostPort("localhost", port) + "/subject")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Empty(t, string(body), "the subjects of unverified certificates are chosen by the clients")
+}
+
+func TestClientAuthRequiresCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "server", nil))
+
+	for _, clientAuth := range []string{"verify-if-given", "require-and-verify"} {
+		s, err := ginserver.New(ginserver.Config{
+			Mode:          "dev",
+			TLSCertFile:   certFile,
+			TLSKeyFile:    keyFile,
+			TLSClientAuth: clientAuth,
+		}, ginserver.WithLogger(otelzap.New(zap.NewNop())))
+		require.NoError(t, err)
+		require.ErrorContains(t, s.Start(), "requires a client CA file", clientAuth)
+	}
+}
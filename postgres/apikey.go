@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lysoul/gocommon/monitoring"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+	"go.uber.org/zap"
+)
+
+// APIKey is a row of the api_keys table, see APIKeyStore.
+// Only the salted hash of the secret of a key is stored.
+type APIKey struct {
+	bun.BaseModel `bun:"table:api_keys,alias:ak"`
+
+	ID         string    `bun:",pk"`
+	Name       string    `bun:",notnull"`
+	Subject    string    `bun:",notnull"`
+	Salt       []byte    `bun:",notnull"`
+	Hash       []byte    `bun:",notnull"`
+	Scopes     []string  `bun:",array"`
+	ExpiresAt  time.Time `bun:",nullzero"`
+	LastUsedAt time.Time `bun:",nullzero"`
+	RevokedAt  time.Time `bun:",nullzero"`
+	CreatedAt  time.Time `bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+func (k *APIKey) toShared() *shared.APIKey {
+	return &shared.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Subject:    k.Subject,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+	}
+}
+
+// AddAPIKeyMigration adds the migration creating the api_keys table,
+// e.g. to the migrations given to CliCommand or Connect.
+func AddAPIKeyMigration(migrations *migrate.Migrations) {
+	migrations.Add(migrate.Migration{
+		Name:    "20251201000000",
+		Comment: "api_keys",
+		Up: func(ctx context.Context, db *bun.DB, _ any) error {
+			if _, err := db.NewCreateTable().Model((*APIKey)(nil)).IfNotExists().Exec(ctx); err != nil {
+				return err
+			}
+			_, err := db.NewCreateIndex().Model((*APIKey)(nil)).IfNotExists().
+				Index("api_keys_subject_idx").Column("subject").Exec(ctx)
+			return err
+		},
+		Down: func(ctx context.Context, db *bun.DB, _ any) error {
+			_, err := db.NewDropTable().Model((*APIKey)(nil)).IfExists().Exec(ctx)
+			return err
+		},
+	})
+}
+
+// APIKeyStore is a shared.APIKeyStore storing keys in the api_keys table.
+type APIKeyStore struct {
+	db     bun.IDB
+	prefix string
+	// last_used_at is updated at most once per interval, not on every request
+	lastUsedInterval time.Duration
+}
+
+var _ shared.APIKeyStore = (*APIKeyStore)(nil)
+
+// NewAPIKeyStore creates a store of keys starting with prefix (e.g. "sk", may be empty).
+func NewAPIKeyStore(db bun.IDB, prefix string) *APIKeyStore {
+	return &APIKeyStore{db: db, prefix: prefix, lastUsedInterval: time.Minute}
+}
+
+// Create issues a key to subject. The returned key is the only copy of its secret.
+// A zero expiresAt never expires.
+func (s *APIKeyStore) Create(
+	ctx context.Context,
+	subject, name string,
+	scopes []string,
+	expiresAt time.Time,
+) (string, *shared.APIKey, error) {
+	key, id, secret, err := shared.GenerateAPIKey(s.prefix)
+	if err != nil {
+		return "", nil, err
+	}
+	salt, err := shared.NewAPIKeySalt()
+	if err != nil {
+		return "", nil, err
+	}
+	row := &APIKey{
+		ID:        id,
+		Name:      name,
+		Subject:   subject,
+		Salt:      salt,
+		Hash:      shared.HashAPIKey(secret, salt),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if _, err := s.db.NewInsert().Model(row).Exec(ctx); err != nil {
+		return "", nil, fmt.Errorf("create API key: %w", err)
+	}
+	return key, row.toShared(), nil
+}
+
+// Authenticate returns the key matching key, unless it is revoked or expired.
+func (s *APIKeyStore) Authenticate(ctx context.Context, key string) (*shared.APIKey, error) {
+	id, secret, ok := shared.ParseAPIKey(key)
+	if !ok {
+		return nil, shared.ErrInvalidAPIKey
+	}
+
+	row := &APIKey{}
+	err := s.db.NewSelect().Model(row).
+		Where("ak.id = ?", id).
+		Where("ak.revoked_at IS NULL").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, shared.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find API key: %w", err)
+	}
+
+	now := time.Now()
+	if !shared.VerifyAPIKey(secret, row.Salt, row.Hash) || row.toShared().Expired(now) {
+		return nil, shared.ErrInvalidAPIKey
+	}
+
+	if now.Sub(row.LastUsedAt) > s.lastUsedInterval {
+		_, err := s.db.NewUpdate().Model((*APIKey)(nil)).
+			Set("last_used_at = ?", now).
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			// the key is valid, do not fail the request
+			monitoring.Logger().Warn("failed to update API key last use", zap.String("id", id), zap.Error(err))
+		} else {
+			row.LastUsedAt = now
+		}
+	}
+	return row.toShared(), nil
+}
+
+// Revoke revokes the key id, it can no longer be used.
+func (s *APIKeyStore) Revoke(ctx context.Context, id string) error {
+	_, err := s.db.NewUpdate().Model((*APIKey)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+// List returns the keys of subject that are not revoked.
+func (s *APIKeyStore) List(ctx context.Context, subject string) ([]*shared.APIKey, error) {
+	var rows []*APIKey
+	err := s.db.NewSelect().Model(&rows).
+		Where("ak.subject = ?", subject).
+		Where("ak.revoked_at IS NULL").
+		Order("ak.created_at").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*shared.APIKey, len(rows))
+	for i, row := range rows {
+		keys[i] = row.toShared()
+	}
+	return keys, nil
+}
@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Lysoul/gocommon/postgres"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/migrate"
+)
+
+var apiKeyColumns = []string{
+	"id", "name", "subject", "salt", "hash", "scopes",
+	"expires_at", "last_used_at", "revoked_at", "created_at",
+}
+
+func TestAPIKeyStore(t *testing.T) {
+	db, mock := postgres.ConnectMock(t)
+	store := postgres.NewAPIKeyStore(db, "sk")
+	ctx := context.Background()
+
+	mock.ExpectQuery(`INSERT INTO "api_keys" .* RETURNING`).WillReturnRows(
+		sqlmock.NewRows([]string{"expires_at", "last_used_at", "revoked_at", "created_at"}).
+			AddRow(nil, nil, nil, time.Now()))
+	key, created, err := store.Create(ctx, "billing", "billing service", []string{"invoices:read"}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, "billing", created.Subject)
+
+	id, secret, ok := shared.ParseAPIKey(key)
+	require.True(t, ok)
+	require.Equal(t, created.ID, id)
+	require.Regexp(t, `^sk_[0-9a-f]{16}$`, id)
+
+	salt := []byte("0123456789abcdef")
+	row := func(expiresAt any) *sqlmock.Rows {
+		return sqlmock.NewRows(apiKeyColumns).AddRow(
+			id, "billing service", "billing", salt, shared.HashAPIKey(secret, salt), "{invoices:read}",
+			expiresAt, nil, nil, time.Now())
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM "api_keys" AS "ak" WHERE \(ak.id = 'sk_.*'\) AND \(ak.revoked_at IS NULL\)`).
+			WillReturnRows(row(nil))
+		mock.ExpectExec(`UPDATE "api_keys" AS "ak" SET last_used_at = `).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		got, err := store.Authenticate(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "billing", got.Subject)
+		require.Equal(t, []string{"invoices:read"}, got.Scopes)
+		require.False(t, got.LastUsedAt.IsZero())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM "api_keys"`).WillReturnRows(row(nil))
+		_, err := store.Authenticate(ctx, id+".wrong")
+		require.ErrorIs(t, err, shared.ErrInvalidAPIKey)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM "api_keys"`).WillReturnRows(row(time.Now().Add(-time.Hour)))
+		_, err := store.Authenticate(ctx, key)
+		require.ErrorIs(t, err, shared.ErrInvalidAPIKey)
+	})
+
+	t.Run("unknown or revoked", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM "api_keys"`).WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+		_, err := store.Authenticate(ctx, key)
+		require.ErrorIs(t, err, shared.ErrInvalidAPIKey)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := store.Authenticate(ctx, "no-secret")
+		require.ErrorIs(t, err, shared.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE "api_keys" AS "ak" SET revoked_at = .* WHERE \(id = 'sk_.*'\)`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, store.Revoke(ctx, id))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyMigration(t *testing.T) {
+	migrations := migrate.NewMigrations()
+	postgres.AddAPIKeyMigration(migrations)
+	sorted := migrations.Sorted()
+	require.Len(t, sorted, 1)
+	require.Equal(t, "20251201000000_api_keys", sorted[0].String())
+
+	db, mock := postgres.ConnectMock(t)
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "api_keys"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS "api_keys_subject_idx" ON "api_keys" \("subject"\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, sorted[0].Up(context.Background(), db, nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
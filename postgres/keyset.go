@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/uptrace/bun"
+)
+
+// SortKey is a column of the order of a list paginated by cursor, e.g. {Column: "o.created_at", Desc: true}.
+// Sort keys must not be null.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Keyset paginates select queries by cursor (keyset pagination): pages start after the values of the sort keys
+// of the last row of the previous page, instead of skipping rows, and need no COUNT(*).
+type Keyset struct {
+	keys   []SortKey
+	cursor shared.Cursor
+	limit  int
+}
+
+// NewKeyset decodes the cursor of page for a list ordered by keys, or fails with shared.ErrInvalidCursor.
+// The last key must be unique (e.g. the primary key) for the order to be total.
+func NewKeyset(page shared.CursorPagination, keys ...SortKey) (*Keyset, error) {
+	k := &Keyset{keys: keys, limit: page.PageLimit()}
+	if page.Cursor == "" {
+		return k, nil
+	}
+	cursor, err := shared.DecodeCursor(page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(cursor.Values) != len(keys) {
+		return nil, shared.ErrInvalidCursor
+	}
+	k.cursor = cursor
+	return k, nil
+}
+
+// Apply adds the keyset condition, the order and the limit to q.
+// One more row than the limit is selected, to know whether there is a next page.
+func (k *Keyset) Apply(q *bun.SelectQuery) *bun.SelectQuery {
+	if len(k.cursor.Values) > 0 {
+		// (a, b) > (x, y) expanded to (a > x) OR (a = x AND b > y), for keys in mixed directions
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for i, key := range k.keys {
+				q = q.WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+					for j := range i {
+						q = q.Where("? = ?", bun.Ident(k.keys[j].Column), k.cursor.Values[j])
+					}
+					op := "? > ?"
+					if key.Desc != k.cursor.Before {
+						op = "? < ?"
+					}
+					return q.Where(op, bun.Ident(key.Column), k.cursor.Values[i])
+				})
+			}
+			return q
+		})
+	}
+	for _, key := range k.keys {
+		// previous pages are selected in reverse order, from the cursor
+		if key.Desc != k.cursor.Before {
+			q = q.OrderExpr("? DESC", bun.Ident(key.Column))
+		} else {
+			q = q.OrderExpr("? ASC", bun.Ident(key.Column))
+		}
+	}
+	return q.Limit(k.limit + 1)
+}
+
+// KeysetPage returns the page of rows selected by a query applied by k,
+// values returns the values of the sort keys of a row in the order of the keys.
+func KeysetPage[T any](k *Keyset, rows []T, values func(T) []any) (shared.CursorPage[T], error) {
+	more := len(rows) > k.limit
+	if more {
+		rows = rows[:k.limit]
+	}
+	page := shared.CursorPage[T]{Items: rows}
+	if len(rows) == 0 {
+		page.Items = []T{}
+		return page, nil
+	}
+
+	hasNext, hasPrev := more, len(k.cursor.Values) > 0
+	if k.cursor.Before {
+		slices.Reverse(rows)
+		hasNext, hasPrev = true, more
+	}
+	var err error
+	if hasNext {
+		if page.NextCursor, err = shared.EncodeCursor(shared.Cursor{Values: values(rows[len(rows)-1])}); err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = shared.EncodeCursor(shared.Cursor{Values: values(rows[0]), Before: true}); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// SelectCursorPage selects the page of q requested by page, ordered by keys. q selects the table, e.g.
+//
+//	db.NewSelect().Model((*Order)(nil)).Where("o.customer_id = ?", customerID)
+//
+// and values returns the values of the sort keys of a row.
+func SelectCursorPage[T any](
+	ctx context.Context,
+	q *bun.SelectQuery,
+	page shared.CursorPagination,
+	values func(T) []any,
+	keys ...SortKey,
+) (shared.CursorPage[T], error) {
+	k, err := NewKeyset(page, keys...)
+	if err != nil {
+		return shared.CursorPage[T]{}, err
+	}
+	var rows []T
+	if err := k.Apply(q).Scan(ctx, &rows); err != nil {
+		return shared.CursorPage[T]{}, err
+	}
+	return KeysetPage(k, rows, values)
+}
@@ -0,0 +1,92 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Lysoul/gocommon/postgres"
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+type order struct {
+	bun.BaseModel `bun:"table:orders,alias:o"`
+
+	ID        int64 `bun:",pk"`
+	CreatedAt time.Time
+}
+
+func orderKeys(o order) []any {
+	return []any{o.CreatedAt, o.ID}
+}
+
+func TestSelectCursorPage(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, shared.InitCursors())
+	db, mock := postgres.ConnectMock(t)
+	ctx := context.Background()
+	keys := []postgres.SortKey{{Column: "o.created_at", Desc: true}, {Column: "o.id"}}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := func(ids ...int64) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"id", "created_at"})
+		for _, id := range ids {
+			rows.AddRow(id, day)
+		}
+		return rows
+	}
+	selectPage := func(page shared.CursorPagination) shared.CursorPage[order] {
+		t.Helper()
+		got, err := postgres.SelectCursorPage(ctx, db.NewSelect().Model((*order)(nil)), page, orderKeys, keys...)
+		require.NoError(t, err)
+		return got
+	}
+	ids := func(page shared.CursorPage[order]) []int64 {
+		var ids []int64
+		for _, o := range page.Items {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+
+	mock.ExpectQuery(`^SELECT .* FROM "orders" AS "o" ORDER BY "o"."created_at" DESC, "o"."id" ASC LIMIT 3$`).
+		WillReturnRows(rows(1, 2, 3))
+	first := selectPage(shared.CursorPagination{Limit: 2})
+	require.Equal(t, []int64{1, 2}, ids(first))
+	require.NotEmpty(t, first.NextCursor)
+	require.Empty(t, first.PrevCursor, "the first page has no previous page")
+
+	mock.ExpectQuery(`WHERE \(\(\("o"."created_at" < '2024-05-01T00:00:00Z'\)\) OR ` +
+		`\(\("o"."created_at" = '2024-05-01T00:00:00Z'\) AND \("o"."id" > 2\)\)\) ` +
+		`ORDER BY "o"."created_at" DESC, "o"."id" ASC LIMIT 3$`).
+		WillReturnRows(rows(3, 4))
+	second := selectPage(shared.CursorPagination{Cursor: first.NextCursor, Limit: 2})
+	require.Equal(t, []int64{3, 4}, ids(second))
+	require.Empty(t, second.NextCursor, "the last page has no next page")
+	require.NotEmpty(t, second.PrevCursor)
+
+	// previous pages are selected in reverse order
+	mock.ExpectQuery(`WHERE \(\(\("o"."created_at" > '2024-05-01T00:00:00Z'\)\) OR ` +
+		`\(\("o"."created_at" = '2024-05-01T00:00:00Z'\) AND \("o"."id" < 3\)\)\) ` +
+		`ORDER BY "o"."created_at" ASC, "o"."id" DESC LIMIT 3$`).
+		WillReturnRows(rows(2, 1))
+	prev := selectPage(shared.CursorPagination{Cursor: second.PrevCursor, Limit: 2})
+	require.Equal(t, []int64{1, 2}, ids(prev))
+	require.Empty(t, prev.PrevCursor)
+	require.NotEmpty(t, prev.NextCursor)
+
+	mock.ExpectQuery(`ORDER BY "o"."created_at" DESC, "o"."id" ASC LIMIT 21$`).WillReturnRows(rows())
+	empty := selectPage(shared.CursorPagination{})
+	require.Equal(t, shared.CursorPage[order]{Items: []order{}}, empty)
+
+	_, err := postgres.SelectCursorPage(ctx, db.NewSelect().Model((*order)(nil)),
+		shared.CursorPagination{Cursor: "forged"}, orderKeys, keys...)
+	require.ErrorIs(t, err, shared.ErrInvalidCursor)
+	_, err = postgres.SelectCursorPage(ctx, db.NewSelect().Model((*order)(nil)),
+		shared.CursorPagination{Cursor: first.NextCursor}, orderKeys, keys[0])
+	require.ErrorIs(t, err, shared.ErrInvalidCursor, "cursors of other orders are invalid")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
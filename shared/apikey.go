@@ -0,0 +1,83 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAPIKey is returned by key stores for unknown, revoked or expired keys.
+// It matches ErrUnauthorized.
+//
+//nolint:gochecknoglobals // sentinel error.
+var ErrInvalidAPIKey = &Error{Kind: ErrUnauthorized, Message: "invalid API key"}
+
+// APIKey is a stored API key, without its secret.
+type APIKey struct {
+	// public part of the key, used to look it up
+	ID   string
+	Name string
+	// the client the key was issued to
+	Subject    string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Expired reports whether the key expired at now, keys with a zero ExpiresAt never expire.
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// APIKeyStore authenticates API keys, e.g. postgres.APIKeyStore.
+type APIKeyStore interface {
+	// Authenticate returns the key matching key, an error wrapping ErrInvalidAPIKey when there is none.
+	Authenticate(ctx context.Context, key string) (*APIKey, error)
+}
+
+// GenerateAPIKey generates a key made of a random ID and secret, "<prefix>_<id>.<secret>".
+// Store the ID and HashAPIKey(secret, salt), the key is only shown once.
+func GenerateAPIKey(prefix string) (key, id, secret string, err error) {
+	b := make([]byte, 8+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	id = hex.EncodeToString(b[:8])
+	if prefix != "" {
+		id = prefix + "_" + id
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b[8:])
+	return id + "." + secret, id, secret, nil
+}
+
+// ParseAPIKey splits a key generated by GenerateAPIKey.
+func ParseAPIKey(key string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(key, ".")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// NewAPIKeySalt returns a random salt for HashAPIKey.
+func NewAPIKeySalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// HashAPIKey returns the salted hash of the secret of a key.
+// Secrets are random, a fast hash is then as safe as a password hash.
+func HashAPIKey(secret string, salt []byte) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+// VerifyAPIKey reports whether secret matches hash, in constant time.
+func VerifyAPIKey(secret string, salt, hash []byte) bool {
+	return subtle.ConstantTimeCompare(HashAPIKey(secret, salt), hash) == 1
+}
@@ -0,0 +1,27 @@
+package shared
+
+import "context"
+
+// SubjectContextKey is the gin context key of the authenticated subject
+// (user or client ID), set by authentication middlewares.
+const SubjectContextKey = "subject"
+
+// APIKeyContextKey is the gin context key of the authenticated *APIKey,
+// set by API key authentication middlewares.
+const APIKeyContextKey = "api_key"
+
+// LanguageContextKey is the gin context key of the language negotiated for the request.
+const LanguageContextKey = "language"
+
+type languageContextKey struct{}
+
+// WithLanguage returns a copy of ctx carrying the language of the request.
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageContextKey{}, language)
+}
+
+// LanguageFromContext returns the language of the request of ctx, empty if it was not negotiated.
+func LanguageFromContext(ctx context.Context) string {
+	language, _ := ctx.Value(languageContextKey{}).(string)
+	return language
+}
@@ -0,0 +1,216 @@
+package shared
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// ErrInvalidCursor is returned for cursors that were not encoded by the service (or with another secret).
+// It matches ErrValidationFailed.
+//
+//nolint:gochecknoglobals // sentinel error.
+var ErrInvalidCursor = &Error{Kind: ErrValidationFailed, Message: "invalid cursor"}
+
+type CursorConfig struct {
+	// secret signing the cursors, cursors signed with another secret are invalid
+	Secret string `envconfig:"CURSOR_SECRET" required:"true"`
+}
+
+type PaginationConfig struct {
+	// limit of the pages requested without limit
+	DefaultLimit int `envconfig:"PAGINATION_DEFAULT_LIMIT" default:"20"`
+	// max limit of the pages, larger limits are lowered to it
+	MaxLimit int `envconfig:"PAGINATION_MAX_LIMIT" default:"50"`
+}
+
+// MinCursorSecretLength is the min length of the secrets signing the cursors, the size of a SHA-256 key.
+const MinCursorSecretLength = 32
+
+//nolint:gochecknoglobals // sync.OnceValues is safe for concurrent use by multiple goroutines.
+var cursorCodec = sync.OnceValues(func() (*CursorCodec, error) {
+	c := CursorConfig{}
+	if err := envconfig.Process("", &c); err != nil {
+		return nil, fmt.Errorf("shared: cursors: %w", err)
+	}
+	return NewCursorCodec([]byte(c.Secret))
+})
+
+// InitCursors checks CURSOR_SECRET, call it at startup so that a missing or short secret
+// stops the service instead of failing the requests of paginated lists.
+func InitCursors() error {
+	_, err := cursorCodec()
+	return err
+}
+
+//nolint:gochecknoglobals // sync.OnceValue is safe for concurrent use by multiple goroutines.
+var paginationConfig = sync.OnceValue(func() PaginationConfig {
+	c := PaginationConfig{}
+	envconfig.MustProcess("", &c)
+	return c
+})
+
+// Cursor is a position in a list sorted by keys (keyset pagination):
+// the values of the sort keys of the item the page starts after, or ends before.
+type Cursor struct {
+	// Values of the sort keys. Once decoded, numbers are int64 or float64 and times are RFC 3339 strings.
+	Values []any `json:"v"`
+	// Before is true for the cursors of previous pages, which end before the position.
+	Before bool `json:"b,omitempty"`
+}
+
+// EncodeCursor encodes c into an opaque token signed with CURSOR_SECRET.
+func EncodeCursor(c Cursor) (string, error) {
+	codec, err := cursorCodec()
+	if err != nil {
+		return "", err
+	}
+	return codec.Encode(c)
+}
+
+// DecodeCursor decodes a token encoded by EncodeCursor, or fails with ErrInvalidCursor.
+func DecodeCursor(token string) (Cursor, error) {
+	codec, err := cursorCodec()
+	if err != nil {
+		return Cursor{}, err
+	}
+	return codec.Decode(token)
+}
+
+// CursorCodec encodes cursors as tokens signed with HMAC-SHA256, clients can't forge positions.
+type CursorCodec struct {
+	secret []byte
+}
+
+// NewCursorCodec returns a codec signing with secret, which has at least MinCursorSecretLength bytes.
+func NewCursorCodec(secret []byte) (*CursorCodec, error) {
+	if len(secret) < MinCursorSecretLength {
+		return nil, errors.New("shared: cursor secret must have at least " +
+			strconv.Itoa(MinCursorSecretLength) + " bytes")
+	}
+	return &CursorCodec{secret: secret}, nil
+}
+
+// Encode encodes c into an opaque token.
+func (cc *CursorCodec) Encode(c Cursor) (string, error) {
+	values := make([]any, len(c.Values))
+	for i, v := range c.Values {
+		value, err := cursorValue(v)
+		if err != nil {
+			return "", err
+		}
+		values[i] = value
+	}
+	payload, err := json.Marshal(Cursor{Values: values, Before: c.Before})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(cc.sign(payload)), nil
+}
+
+// Decode decodes a token encoded by Encode, or fails with ErrInvalidCursor.
+func (cc *CursorCodec) Decode(token string) (Cursor, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, cc.sign(payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	for i, v := range c.Values {
+		if n, ok := v.(json.Number); ok {
+			if c.Values[i], err = n.Int64(); err != nil {
+				c.Values[i], _ = n.Float64()
+			}
+		}
+	}
+	return c, nil
+}
+
+func (cc *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cc.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// cursorValue converts v to a value encoded as is: sort keys are compared by the database,
+// IDs must not be encoded as hashids.
+func cursorValue(v any) (any, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	default:
+		return v, nil
+	}
+}
+
+// CursorPagination is the request of a page of a list paginated by cursor (keyset pagination).
+// Unlike Pagination it doesn't need to count the items, and pages don't shift when items are inserted.
+type CursorPagination struct {
+	// Cursor is the NextCursor or PrevCursor of a CursorPage, empty for the first page.
+	Cursor string `form:"cursor" json:"cursor,omitempty"`
+	Limit  int    `form:"limit" json:"limit,omitempty" binding:"min=0"`
+}
+
+// PageLimit returns the limit of the page: PAGINATION_DEFAULT_LIMIT without limit,
+// at most PAGINATION_MAX_LIMIT.
+func (p CursorPagination) PageLimit() int {
+	config := paginationConfig()
+	switch {
+	case p.Limit <= 0:
+		return config.DefaultLimit
+	case p.Limit > config.MaxLimit:
+		return config.MaxLimit
+	default:
+		return p.Limit
+	}
+}
+
+// CursorPage is a page of a list paginated by cursor.
+type CursorPage[T interface{}] struct {
+	Items []T `json:"items"`
+	// NextCursor requests the next page, empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// PrevCursor requests the previous page, empty on the first page.
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
@@ -0,0 +1,79 @@
+package shared_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/stretchr/testify/require"
+)
+
+const cursorSecret = "0123456789abcdef0123456789abcdef"
+
+func newCursorCodec(t *testing.T, secret string) *shared.CursorCodec {
+	t.Helper()
+	codec, err := shared.NewCursorCodec([]byte(secret))
+	require.NoError(t, err)
+	return codec
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := newCursorCodec(t, cursorSecret)
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC)
+
+	token, err := codec.Encode(shared.Cursor{Values: []any{createdAt, shared.ID(42), 1.5, "a"}, Before: true})
+	require.NoError(t, err)
+	require.NotContains(t, token, "=", "cursors are safe in query strings")
+
+	cursor, err := codec.Decode(token)
+	require.NoError(t, err)
+	require.Equal(t, shared.Cursor{
+		Values: []any{"2024-05-01T10:30:00.123456Z", int64(42), 1.5, "a"},
+		Before: true,
+	}, cursor, "IDs are not encoded as hashids")
+
+	payload, signature, _ := strings.Cut(token, ".")
+	for _, invalid := range []string{
+		"",
+		payload,
+		payload + ".",
+		payload + "x." + signature,
+		"e30." + signature,
+	} {
+		_, err := codec.Decode(invalid)
+		require.ErrorIs(t, err, shared.ErrInvalidCursor, invalid)
+		require.ErrorIs(t, err, shared.ErrValidationFailed, invalid)
+	}
+
+	_, err = newCursorCodec(t, strings.Repeat("x", 32)).Decode(token)
+	require.ErrorIs(t, err, shared.ErrInvalidCursor, "cursors signed with another secret are invalid")
+}
+
+func TestEncodeCursor(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", cursorSecret)
+	require.NoError(t, shared.InitCursors())
+
+	token, err := shared.EncodeCursor(shared.Cursor{Values: []any{7}})
+	require.NoError(t, err)
+	cursor, err := newCursorCodec(t, cursorSecret).Decode(token)
+	require.NoError(t, err)
+	require.Equal(t, shared.Cursor{Values: []any{int64(7)}}, cursor)
+
+	cursor, err = shared.DecodeCursor(token)
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(7)}, cursor.Values)
+}
+
+func TestNewCursorCodecRejectsShortSecrets(t *testing.T) {
+	for _, secret := range []string{"", "secret", cursorSecret[1:]} {
+		_, err := shared.NewCursorCodec([]byte(secret))
+		require.ErrorContains(t, err, "at least 32 bytes", secret)
+	}
+}
+
+func TestCursorPaginationPageLimit(t *testing.T) {
+	require.Equal(t, 20, shared.CursorPagination{}.PageLimit())
+	require.Equal(t, 10, shared.CursorPagination{Limit: 10}.PageLimit())
+	require.Equal(t, 50, shared.CursorPagination{Limit: 500}.PageLimit())
+}
@@ -1,13 +1,12 @@
 package shared
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"maps"
+	"runtime"
 
-	// "github.com/Lysoul/gocommon/monitoring"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 var (
@@ -16,6 +15,13 @@ var (
 	ErrNotFound         = ConstError("not_found")
 	ErrValidationFailed = ConstError("validation_failed")
 	ErrUnexpected       = ConstError("unexpected")
+
+	ErrPreconditionFailed   = ConstError("precondition_failed")
+	ErrPreconditionRequired = ConstError("precondition_required")
+
+	ErrRateLimited = ConstError("rate_limited")
+
+	ErrRequestTooLarge = ConstError("request_too_large")
 )
 
 type ConstError string
@@ -24,9 +30,8 @@ func (err ConstError) Error() string {
 	return string(err)
 }
 func (err ConstError) Is(target error) bool {
-	ts := target.Error()
-	es := string(err)
-	return ts == es || strings.HasPrefix(ts, es+": ")
+	t, ok := target.(ConstError)
+	return ok && t == err
 }
 func (err ConstError) Wrap(inner error) error {
 	return wrapError{msg: string(err), err: inner}
@@ -50,25 +55,118 @@ func (err wrapError) Is(target error) bool {
 	return ConstError(err.msg).Is(target)
 }
 
-func ErrorToHTTP(ctx *gin.Context, err error) {
-	validationErrs := &validator.ValidationErrors{}
-	switch {
-	case err == nil:
-		return
-	case errors.As(err, validationErrs):
-		ctx.AbortWithStatusJSON(400, gin.H{
-			"error":   "validation_failed",
-			"details": MapValidationErrors(validationErrs),
-		})
-	case errors.Is(err, ErrNotFound),
-		errors.Is(err, ErrPermissionDenied):
-		ctx.AbortWithStatus(404)
+// Error is a structured error.
+// It matches its Kind and errors with the same Code with errors.Is,
+// and unwraps to its Cause.
+type Error struct {
+	// Stable machine readable code, e.g. "user_not_found".
+	Code string
+	// Transport agnostic classification, e.g. ErrNotFound.
+	Kind ConstError
+	// Message that is safe to show to users.
+	Message string
+	// Internal cause, never shown to users.
+	Cause error
+	// Key/value pairs for logging.
+	Meta map[string]any
+
+	stack []uintptr
+}
+
+// NewError returns an error of kind with the stack of the caller.
+func NewError(kind ConstError, code, message string) *Error {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	return &Error{
+		Code:    code,
+		Kind:    kind,
+		Message: message,
+		stack:   pcs[:n],
+	}
+}
+
+// WithCause returns a copy of the error with cause.
+func (err *Error) WithCause(cause error) *Error {
+	e := *err
+	e.Cause = cause
+	return &e
+}
+
+// WithMeta returns a copy of the error with key set to value in its metadata.
+func (err *Error) WithMeta(key string, value any) *Error {
+	e := *err
+	e.Meta = make(map[string]any, len(err.Meta)+1)
+	maps.Copy(e.Meta, err.Meta)
+	e.Meta[key] = value
+	return &e
+}
+
+func (err *Error) Error() string {
+	msg := err.code()
+	if err.Message != "" {
+		msg += ": " + err.Message
+	}
+	if err.Cause != nil {
+		msg += ": " + err.Cause.Error()
+	}
+	return msg
+}
+
+func (err *Error) Unwrap() error {
+	return err.Cause
+}
+
+func (err *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case ConstError:
+		return err.Kind != "" && t == err.Kind
+	case *Error:
+		return t.Code != "" && t.Code == err.Code
 	default:
-		// monitoring.Logger().ErrorContext(ctx, "no http mapping for error",
-		// 	zap.String("type", fmt.Sprintf("%T", err)),
-		// 	zap.Error(err))
+		return false
+	}
+}
+
+// Stack returns the stack captured by NewError.
+func (err *Error) Stack() []runtime.Frame {
+	frames := runtime.CallersFrames(err.stack)
+	stack := []runtime.Frame{}
+	for {
+		frame, more := frames.Next()
+		if frame.PC != 0 {
+			stack = append(stack, frame)
+		}
+		if !more {
+			return stack
+		}
+	}
+}
 
-		ctx.AbortWithStatus(500)
+// MarshalJSON marshals the user safe part of the error as an ErrorResponse.
+func (err *Error) MarshalJSON() ([]byte, error) {
+	msg := err.Message
+	if msg == "" {
+		msg = err.Kind.Error()
+	}
+	return json.Marshal(ErrorResponse{
+		Code:  err.code(),
+		Error: msg,
+	})
+}
+
+func (err *Error) code() string {
+	if err.Code != "" {
+		return err.Code
+	}
+	return string(err.Kind)
+}
+
+// ErrorToHTTP aborts the request with err as problem details, see HTTPErrors.
+// Breaking: ErrPermissionDenied responds with 403 Forbidden like ErrorMiddleware, not 404 anymore,
+// services hiding the existence of resources register it again with 404.
+func ErrorToHTTP(ctx *gin.Context, err error) {
+	if err == nil {
+		return
 	}
-	// todo: only handle errors we know and call shared.handleError(err) for the rest
+	HTTPErrors().Write(ctx, err)
 }
@@ -0,0 +1,58 @@
+package shared_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstError(t *testing.T) {
+	require.ErrorIs(t, shared.ErrNotFound.Wrap(sql.ErrNoRows), shared.ErrNotFound)
+	require.ErrorIs(t, shared.ErrNotFound.Wrap(sql.ErrNoRows), sql.ErrNoRows)
+	require.ErrorIs(t, fmt.Errorf("get user: %w", shared.ErrNotFound), shared.ErrNotFound)
+
+	// messages that look like a const error are not the const error
+	require.NotErrorIs(t, errors.New("not_found: user"), shared.ErrNotFound)
+	require.NotErrorIs(t, shared.ErrNotFound, errors.New("not_found"))
+}
+
+func TestError(t *testing.T) {
+	errUserNotFound := shared.NewError(shared.ErrNotFound, "user_not_found", "user does not exist")
+
+	err := fmt.Errorf("get user: %w", errUserNotFound.
+		WithCause(sql.ErrNoRows).
+		WithMeta("user_id", 1))
+
+	t.Run("Is", func(t *testing.T) {
+		require.ErrorIs(t, err, shared.ErrNotFound)
+		require.ErrorIs(t, err, errUserNotFound)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.NotErrorIs(t, err, shared.ErrPermissionDenied)
+		require.NotErrorIs(t, err, shared.NewError(shared.ErrNotFound, "post_not_found", ""))
+	})
+
+	t.Run("As", func(t *testing.T) {
+		sharedErr := &shared.Error{}
+		require.ErrorAs(t, err, &sharedErr)
+		require.Equal(t, "user_not_found", sharedErr.Code)
+		require.Equal(t, map[string]any{"user_id": 1}, sharedErr.Meta)
+		require.Nil(t, errUserNotFound.Meta)
+		require.NotEmpty(t, sharedErr.Stack())
+		require.Equal(t, "github.com/Lysoul/gocommon/shared_test.TestError", sharedErr.Stack()[0].Function)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		require.Equal(t, "get user: user_not_found: user does not exist: sql: no rows in result set", err.Error())
+	})
+
+	t.Run("MarshalJSON", func(t *testing.T) {
+		b, err := json.Marshal(errUserNotFound.WithCause(sql.ErrNoRows))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"code":"user_not_found","error":"user does not exist"}`, string(b))
+	})
+}
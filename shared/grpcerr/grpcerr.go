@@ -0,0 +1,309 @@
+// Package grpcerr translates shared errors to gRPC statuses and back.
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// MetaFieldViolations is the shared.Error metadata key holding the
+// validation errors of an InvalidArgument status, formatted like shared.MapValidationErrors.
+const MetaFieldViolations = "field_violations"
+
+// errorInfoKind is the ErrorInfo metadata key of the error kind, which converts statuses back
+// to the kind they came from when several kinds share a code (e.g. FailedPrecondition).
+const errorInfoKind = "kind"
+
+type entry struct {
+	kind shared.ConstError
+	code codes.Code
+}
+
+// Registry maps shared error kinds to gRPC codes.
+// Entries registered last are matched first, so services can override the defaults.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register maps every error matching kind (using errors.Is) to code, and code back to kind.
+func (r *Registry) Register(kind shared.ConstError, code codes.Code) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{kind: kind, code: code})
+}
+
+// Code returns the gRPC code of err, codes.Internal for unknown errors.
+func (r *Registry) Code(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	if e, ok := r.match(err); ok {
+		return e.code
+	}
+	return codes.Internal
+}
+
+// match returns the entry of the kind of err.
+func (r *Registry) match(err error) (entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if errors.As(err, &validator.ValidationErrors{}) {
+		err = shared.ErrValidationFailed
+	}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if errors.Is(err, r.entries[i].kind) {
+			return r.entries[i], true
+		}
+	}
+	return entry{}, false
+}
+
+// Kind returns the shared error kind of code, ErrUnexpected for unknown codes.
+// Codes shared by several kinds return the last registered, statuses created by Status
+// carry their kind and convert back to it.
+func (r *Registry) Kind(code codes.Code) shared.ConstError {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].code == code {
+			return r.entries[i].kind
+		}
+	}
+	return shared.ErrUnexpected
+}
+
+// ambiguous reports whether several kinds map to code.
+func (r *Registry) ambiguous(code codes.Code) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var kind shared.ConstError
+	for _, e := range r.entries {
+		if e.code != code {
+			continue
+		}
+		if kind != "" && e.kind != kind {
+			return true
+		}
+		kind = e.kind
+	}
+	return false
+}
+
+// kind returns the registered kind named name when it maps to code.
+func (r *Registry) kind(name string, code codes.Code) (shared.ConstError, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.entries {
+		if string(e.kind) == name && e.code == code {
+			return e.kind, true
+		}
+	}
+	return "", false
+}
+
+// Status converts err to a gRPC status.
+// Errors that already are statuses are returned as is. The message is the Message of a *shared.Error,
+// or the matched kind, wrapped causes and messages of internal errors are not exposed.
+func (r *Registry) Status(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	code := r.Code(err)
+	matched, isMatched := r.match(err)
+	sharedErr := &shared.Error{}
+	isSharedErr := errors.As(err, &sharedErr)
+
+	// the messages of wrapped causes are internal
+	msg := code.String()
+	switch {
+	case isSharedErr && sharedErr.Message != "":
+		msg = sharedErr.Message
+	case isMatched && matched.code == code && code != codes.Internal && code != codes.Unknown:
+		msg = matched.kind.Error()
+	}
+	st := status.New(code, msg)
+
+	details := []protoadapt.MessageV1{}
+	info := &errdetails.ErrorInfo{}
+	if isSharedErr {
+		info.Reason = sharedErr.Code
+	}
+	if isMatched && matched.code == code && r.ambiguous(code) {
+		info.Metadata = map[string]string{errorInfoKind: string(matched.kind)}
+	}
+	if info.GetReason() != "" || len(info.GetMetadata()) > 0 {
+		details = append(details, info)
+	}
+	validationErrs := validator.ValidationErrors{}
+	validationErr := &shared.ValidationError{}
+	switch {
+	case errors.As(err, &validationErrs):
+		details = append(details, badRequest(shared.ValidationDetails(validationErrs, "en")))
+	case errors.As(err, &validationErr):
+		details = append(details, badRequest(validationErr.Details))
+	}
+	if len(details) == 0 {
+		return st
+	}
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+// Error converts a gRPC status error back to a *shared.Error.
+// Errors that are not statuses are returned as is.
+func (r *Registry) Error(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	switch st.Code() {
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	default:
+	}
+
+	sharedErr := &shared.Error{
+		Kind:    r.Kind(st.Code()),
+		Message: st.Message(),
+		Cause:   err,
+	}
+	for _, d := range st.Details() {
+		switch detail := d.(type) {
+		case *errdetails.ErrorInfo:
+			sharedErr.Code = detail.GetReason()
+			if kind, ok := r.kind(detail.GetMetadata()[errorInfoKind], st.Code()); ok {
+				sharedErr.Kind = kind
+			}
+		case *errdetails.BadRequest:
+			violations := make([]string, len(detail.GetFieldViolations()))
+			for i, v := range detail.GetFieldViolations() {
+				violations[i] = v.GetField() + " " + v.GetDescription()
+			}
+			sharedErr = sharedErr.WithMeta(MetaFieldViolations, violations)
+		}
+	}
+	return sharedErr
+}
+
+// Codes is the registry used by the interceptors.
+//
+//nolint:gochecknoglobals // sync.OnceValue is safe for concurrent use by multiple goroutines.
+var Codes = sync.OnceValue(func() *Registry {
+	r := NewRegistry()
+	r.Register(shared.ErrUnexpected, codes.Internal)
+	r.Register(shared.ErrUnauthorized, codes.Unauthenticated)
+	r.Register(shared.ErrPermissionDenied, codes.PermissionDenied)
+	r.Register(shared.ErrNotFound, codes.NotFound)
+	r.Register(shared.ErrValidationFailed, codes.InvalidArgument)
+	r.Register(shared.ErrPreconditionRequired, codes.FailedPrecondition)
+	r.Register(shared.ErrPreconditionFailed, codes.FailedPrecondition)
+	r.Register(shared.ErrRequestTooLarge, codes.ResourceExhausted)
+	// registered last, ResourceExhausted statuses convert back to ErrRateLimited
+	r.Register(shared.ErrRateLimited, codes.ResourceExhausted)
+	return r
+})
+
+// ToStatus converts err to a gRPC status using Codes.
+func ToStatus(err error) *status.Status {
+	return Codes().Status(err)
+}
+
+// FromStatus converts a gRPC status error to a *shared.Error using Codes.
+func FromStatus(err error) error {
+	return Codes().Error(err)
+}
+
+// UnaryServerInterceptor converts errors returned by handlers to gRPC statuses.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor converts errors returned by stream handlers to gRPC statuses.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+// UnaryClientInterceptor converts gRPC status errors to shared errors.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return FromStatus(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor converts gRPC status errors returned when opening streams to shared errors.
+// Errors of RecvMsg and SendMsg should be converted with FromStatus.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromStatus(err)
+		}
+		return cs, nil
+	}
+}
+
+func badRequest(details []shared.ValidationDetail) *errdetails.BadRequest {
+	br := &errdetails.BadRequest{}
+	for _, d := range details {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       d.Field,
+			Description: d.Message,
+		})
+	}
+	return br
+}
@@ -0,0 +1,107 @@
+package grpcerr_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/Lysoul/gocommon/shared/grpcerr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := grpcerr.UnaryServerInterceptor()
+	call := func(err error) *status.Status {
+		_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{},
+			func(context.Context, any) (any, error) {
+				return nil, err
+			})
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		return st
+	}
+
+	t.Run("const errors", func(t *testing.T) {
+		require.Equal(t, codes.NotFound, call(shared.ErrNotFound.Wrap(errors.New("user"))).Code())
+		require.Equal(t, codes.Unauthenticated, call(shared.ErrUnauthorized).Code())
+		require.Equal(t, codes.PermissionDenied, call(shared.ErrPermissionDenied).Code())
+	})
+
+	t.Run("wrapped causes are not exposed", func(t *testing.T) {
+		st := call(shared.ErrNotFound.Wrap(fmt.Errorf("select users: %w", sql.ErrNoRows)))
+		require.Equal(t, codes.NotFound, st.Code())
+		require.Equal(t, "not_found", st.Message())
+	})
+
+	t.Run("internal errors are not exposed", func(t *testing.T) {
+		st := call(errors.New("db is down"))
+		require.Equal(t, codes.Internal, st.Code())
+		require.Equal(t, "Internal", st.Message())
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		type input struct {
+			Name string `validate:"required"`
+		}
+		st := call(shared.Validator().Struct(input{}))
+		require.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+		br, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Equal(t, "name", br.GetFieldViolations()[0].GetField())
+		require.Equal(t, "is required", br.GetFieldViolations()[0].GetDescription())
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	errUserNotFound := shared.NewError(shared.ErrNotFound, "user_not_found", "user does not exist")
+
+	err := grpcerr.FromStatus(grpcerr.ToStatus(errUserNotFound).Err())
+	require.ErrorIs(t, err, shared.ErrNotFound)
+	require.ErrorIs(t, err, errUserNotFound)
+
+	sharedErr := &shared.Error{}
+	require.ErrorAs(t, err, &sharedErr)
+	require.Equal(t, "user does not exist", sharedErr.Message)
+
+	t.Run("validation errors", func(t *testing.T) {
+		type input struct {
+			Name string `validate:"required"`
+		}
+		err := grpcerr.FromStatus(grpcerr.ToStatus(shared.Validator().Struct(input{})).Err())
+		require.ErrorIs(t, err, shared.ErrValidationFailed)
+		require.ErrorAs(t, err, &sharedErr)
+		require.Equal(t, []string{"name is required"}, sharedErr.Meta[grpcerr.MetaFieldViolations])
+	})
+
+	t.Run("request too large", func(t *testing.T) {
+		require.Equal(t, codes.ResourceExhausted, grpcerr.ToStatus(shared.ErrRequestTooLarge.Wrap(errors.New("1 MiB"))).Code())
+	})
+
+	t.Run("kinds sharing a code", func(t *testing.T) {
+		for _, kind := range []shared.ConstError{
+			shared.ErrPreconditionRequired, shared.ErrPreconditionFailed,
+			shared.ErrRequestTooLarge, shared.ErrRateLimited,
+		} {
+			err := grpcerr.FromStatus(grpcerr.ToStatus(kind.Wrap(errors.New("detail"))).Err())
+			require.ErrorIs(t, err, kind)
+			require.ErrorAs(t, err, &sharedErr)
+			require.Empty(t, sharedErr.Code, "the kind is not a code")
+		}
+		require.ErrorIs(t, grpcerr.FromStatus(status.Error(codes.FailedPrecondition, "stale")), shared.ErrPreconditionFailed,
+			"statuses of other services use the last registered kind")
+	})
+
+	t.Run("non status errors", func(t *testing.T) {
+		err := errors.New("dial failed")
+		require.Equal(t, err, grpcerr.FromStatus(err))
+		require.NoError(t, grpcerr.FromStatus(nil))
+	})
+}
@@ -0,0 +1,246 @@
+package shared
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details document.
+// see https://www.rfc-editor.org/rfc/rfc9457.html
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// extension members
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	// Errors are the []ValidationDetail of validation errors.
+	Errors any `json:"errors,omitempty"`
+}
+
+// ErrorMapping describes how an error is exposed over HTTP.
+type ErrorMapping struct {
+	// HTTP status code of the response.
+	Status int
+	// Public error code, clients can rely on it.
+	Code string
+	// Problem type URI, defaults to about:blank.
+	Type string
+	// Level the error is logged at.
+	LogLevel zapcore.Level
+	// Whether the messages of structured errors (and validation errors) are sent to the client.
+	ExposeDetails bool
+}
+
+type errorEntry struct {
+	target  error
+	mapping ErrorMapping
+}
+
+// ErrorRegistry maps errors to HTTP responses.
+// Entries registered last are matched first, so services can override the defaults.
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	entries []errorEntry
+}
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{}
+}
+
+// Register maps every error matching target (using errors.Is).
+func (r *ErrorRegistry) Register(target error, mapping ErrorMapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, errorEntry{target: target, mapping: mapping})
+}
+
+// Lookup returns the mapping of err.
+// Validator errors use the mapping of ErrValidationFailed,
+// unknown errors use the mapping of ErrUnexpected (or a plain 500 if it isn't registered).
+func (r *ErrorRegistry) Lookup(err error) ErrorMapping {
+	entry, _ := r.resolve(err)
+	return entry.mapping
+}
+
+// resolve returns the entry matching err, and whether err is mapped rather than unexpected.
+func (r *ErrorRegistry) resolve(err error) (errorEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if entry, ok := r.lookup(err); ok {
+		return entry, true
+	}
+	if errors.As(err, &validator.ValidationErrors{}) {
+		if entry, ok := r.lookup(ErrValidationFailed); ok {
+			return entry, true
+		}
+	}
+	if entry, ok := r.lookup(ErrUnexpected); ok {
+		return entry, false
+	}
+	return errorEntry{
+		target: ErrUnexpected,
+		mapping: ErrorMapping{
+			Status:   http.StatusInternalServerError,
+			Code:     ErrUnexpected.Error(),
+			LogLevel: zapcore.ErrorLevel,
+		},
+	}, false
+}
+
+func (r *ErrorRegistry) lookup(err error) (errorEntry, bool) {
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if errors.Is(err, r.entries[i].target) {
+			return r.entries[i], true
+		}
+	}
+	return errorEntry{}, false
+}
+
+// Problem returns the problem details of err for the request.
+func (r *ErrorRegistry) Problem(ctx *gin.Context, err error) *Problem {
+	entry, mapped := r.resolve(err)
+	m := entry.mapping
+	p := &Problem{
+		Type:      m.Type,
+		Title:     http.StatusText(m.Status),
+		Status:    m.Status,
+		Instance:  ctx.Request.URL.Path,
+		Code:      m.Code,
+		RequestID: ctx.Request.Header.Get("X-Request-ID"),
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if sc := trace.SpanFromContext(ctx.Request.Context()).SpanContext(); sc.IsValid() {
+		p.TraceID = sc.TraceID().String()
+	}
+
+	// structured errors carry their own code and a user safe message,
+	// the codes of unexpected errors are internal
+	sharedErr := &Error{}
+	isSharedErr := errors.As(err, &sharedErr)
+	if isSharedErr && sharedErr.Code != "" && (mapped || m.ExposeDetails) {
+		p.Code = sharedErr.Code
+	}
+
+	// the messages of wrapped causes are internal, only the message of
+	// a structured error or the text of the matched error are exposed
+	if m.ExposeDetails {
+		if isSharedErr && sharedErr.Message != "" {
+			p.Detail = sharedErr.Message
+		} else {
+			p.Detail = entry.target.Error()
+		}
+		validationErrs := validator.ValidationErrors{}
+		detailsErr := &ValidationError{}
+		switch {
+		case errors.As(err, &validationErrs):
+			p.Errors = ValidationDetails(validationErrs, LanguageFromContext(ctx.Request.Context()))
+		case errors.As(err, &detailsErr):
+			p.Errors = detailsErr.Details
+		}
+	}
+	return p
+}
+
+// Write logs err at its mapped level and aborts the request with its problem details.
+func (r *ErrorRegistry) Write(ctx *gin.Context, err error) {
+	p := r.Problem(ctx, err)
+	if ce := zap.L().Check(r.Lookup(err).LogLevel, "http error"); ce != nil {
+		fields := []zap.Field{
+			zap.Int("status", p.Status),
+			zap.String("code", p.Code),
+			zap.String("request_id", p.RequestID),
+			zap.Error(err),
+		}
+		sharedErr := &Error{}
+		if errors.As(err, &sharedErr) && len(sharedErr.Meta) > 0 {
+			fields = append(fields, zap.Any("meta", sharedErr.Meta))
+		}
+		ce.Write(fields...)
+	}
+	WriteProblem(ctx, p)
+}
+
+// WriteProblem aborts the request with p as the response.
+func WriteProblem(ctx *gin.Context, p *Problem) {
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// HTTPErrors is the registry used by ErrorToHTTP and ginserver.ErrorMiddleware.
+//
+//nolint:gochecknoglobals // sync.OnceValue is safe for concurrent use by multiple goroutines.
+var HTTPErrors = sync.OnceValue(func() *ErrorRegistry {
+	r := NewErrorRegistry()
+	r.Register(ErrUnexpected, ErrorMapping{
+		Status:   http.StatusInternalServerError,
+		Code:     ErrUnexpected.Error(),
+		LogLevel: zapcore.ErrorLevel,
+	})
+	r.Register(ErrUnauthorized, ErrorMapping{
+		Status:        http.StatusUnauthorized,
+		Code:          ErrUnauthorized.Error(),
+		LogLevel:      zapcore.DebugLevel,
+		ExposeDetails: true,
+	})
+	// services hiding the existence of resources register it again with 404
+	r.Register(ErrPermissionDenied, ErrorMapping{
+		Status:        http.StatusForbidden,
+		Code:          ErrPermissionDenied.Error(),
+		LogLevel:      zapcore.InfoLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrNotFound, ErrorMapping{
+		Status:        http.StatusNotFound,
+		Code:          ErrNotFound.Error(),
+		LogLevel:      zapcore.DebugLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrValidationFailed, ErrorMapping{
+		Status:        http.StatusBadRequest,
+		Code:          ErrValidationFailed.Error(),
+		LogLevel:      zapcore.DebugLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrPreconditionFailed, ErrorMapping{
+		Status:        http.StatusPreconditionFailed,
+		Code:          ErrPreconditionFailed.Error(),
+		LogLevel:      zapcore.DebugLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrPreconditionRequired, ErrorMapping{
+		Status:        http.StatusPreconditionRequired,
+		Code:          ErrPreconditionRequired.Error(),
+		LogLevel:      zapcore.DebugLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrRequestTooLarge, ErrorMapping{
+		Status:        http.StatusRequestEntityTooLarge,
+		Code:          ErrRequestTooLarge.Error(),
+		LogLevel:      zapcore.InfoLevel,
+		ExposeDetails: true,
+	})
+	r.Register(ErrRateLimited, ErrorMapping{
+		Status:   http.StatusTooManyRequests,
+		Code:     ErrRateLimited.Error(),
+		LogLevel: zapcore.InfoLevel,
+	})
+	return r
+})
@@ -0,0 +1,162 @@
+package shared
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FallbackLanguages are tried by LocalizedText.Get after the requested languages.
+//
+//nolint:gochecknoglobals // set once at startup, like gin.SetMode.
+var FallbackLanguages = []string{"en"}
+
+// LocalizedText is a text in several languages, keyed by language tag (en, th, zh-Hant).
+// It replaces TranslationText and is stored as jsonb.
+type LocalizedText map[string]string
+
+// Lookup returns the text in the first of languages it has. A language falls back
+// to its base language, th-TH to th.
+func (t LocalizedText) Lookup(languages ...string) (string, bool) {
+	for _, language := range languages {
+		for tag := language; tag != ""; tag = TruncateLanguageTag(tag) {
+			if text, ok := t[tag]; ok && text != "" {
+				return text, true
+			}
+			for key, text := range t {
+				if text != "" && strings.EqualFold(key, tag) {
+					return text, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Get returns the text in the first of languages it has, then in FallbackLanguages,
+// then in any language (the first by tag order). It is empty only if t is.
+func (t LocalizedText) Get(languages ...string) string {
+	if text, ok := t.Lookup(languages...); ok {
+		return text
+	}
+	if text, ok := t.Lookup(FallbackLanguages...); ok {
+		return text
+	}
+	for _, key := range slices.Sorted(maps.Keys(t)) {
+		if t[key] != "" {
+			return t[key]
+		}
+	}
+	return ""
+}
+
+// Localize returns the text in the language of the request of ctx, see Get and LanguageFromContext.
+func (t LocalizedText) Localize(ctx context.Context) string {
+	return t.Get(LanguageFromContext(ctx))
+}
+
+// Collapse returns t marshalling to JSON as its text in one of languages, see Get.
+func (t LocalizedText) Collapse(languages ...string) LocalizedString {
+	return LocalizedString{Text: t, Languages: languages}
+}
+
+// UnmarshalJSON accepts an object of texts by language, or a single string
+// stored as the text of the first FallbackLanguages.
+func (t *LocalizedText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = LocalizedText{}
+		if len(FallbackLanguages) > 0 && text != "" {
+			(*t)[FallbackLanguages[0]] = text
+		}
+		return nil
+	}
+	var texts map[string]string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return err
+	}
+	*t = texts
+	return nil
+}
+
+// Value implements driver.Valuer, a nil text is NULL.
+func (t LocalizedText) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(map[string]string(t))
+}
+
+// Scan implements sql.Scanner for json and jsonb columns.
+func (t *LocalizedText) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return t.UnmarshalJSON(src)
+	case string:
+		return t.UnmarshalJSON([]byte(src))
+	default:
+		return fmt.Errorf("cannot scan %T into LocalizedText", src)
+	}
+}
+
+// LocalizedString is a LocalizedText marshalling to JSON as a single string,
+// for responses in the negotiated language. See LocalizedText.Collapse.
+type LocalizedString struct {
+	Text      LocalizedText
+	Languages []string
+}
+
+// String returns the text in one of Languages, see LocalizedText.Get.
+func (s LocalizedString) String() string {
+	return s.Text.Get(s.Languages...)
+}
+
+// MarshalJSON marshals the text in one of Languages, null for an empty text.
+func (s LocalizedString) MarshalJSON() ([]byte, error) {
+	if len(s.Text) == 0 {
+		return json.Marshal(nil)
+	}
+	return json.Marshal(s.String())
+}
+
+// TruncateLanguageTag removes the last subtag of tag, and single letter subtags
+// left at the end (zh-Hant-CN-x-private becomes zh-Hant-CN, then zh-Hant).
+func TruncateLanguageTag(tag string) string {
+	i := strings.LastIndex(tag, "-")
+	if i < 0 {
+		return ""
+	}
+	tag = tag[:i]
+	if j := strings.LastIndex(tag, "-"); j >= 0 && len(tag)-j == 2 {
+		tag = tag[:j]
+	}
+	return tag
+}
+
+// validateLanguages is the languages validation: a LocalizedText must have
+// a non-empty text in each language of the space separated param,
+// e.g. `validate:"languages=en th"`.
+func validateLanguages(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.Map || field.Type().Key().Kind() != reflect.String ||
+		field.Type().Elem().Kind() != reflect.String {
+		return false
+	}
+	for _, language := range strings.Fields(fl.Param()) {
+		value := field.MapIndex(reflect.ValueOf(language).Convert(field.Type().Key()))
+		if !value.IsValid() || value.String() == "" {
+			return false
+		}
+	}
+	return true
+}
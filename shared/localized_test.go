@@ -0,0 +1,95 @@
+package shared_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizedTextGet(t *testing.T) {
+	text := shared.LocalizedText{"en": "Hello", "th": "สวัสดี", "zh-Hant": "你好", "pt-BR": ""}
+
+	require.Equal(t, "สวัสดี", text.Get("th"))
+	require.Equal(t, "สวัสดี", text.Get("th-TH"))
+	require.Equal(t, "你好", text.Get("zh-Hant-TW"))
+	require.Equal(t, "你好", text.Get("fr", "ZH-hant"))
+	// empty texts and unknown languages fall back to FallbackLanguages
+	require.Equal(t, "Hello", text.Get("pt-BR"))
+	require.Equal(t, "Hello", text.Get())
+	require.Equal(t, "Hello", text.Localize(context.Background()))
+	require.Equal(t, "สวัสดี", text.Localize(shared.WithLanguage(context.Background(), "th")))
+
+	_, ok := text.Lookup("fr")
+	require.False(t, ok)
+	// then any language
+	require.Equal(t, "สวัสดี", shared.LocalizedText{"th": "สวัสดี"}.Get("fr"))
+	require.Empty(t, shared.LocalizedText(nil).Get("en"))
+}
+
+func TestTruncateLanguageTag(t *testing.T) {
+	require.Equal(t, "zh-Hant-CN", shared.TruncateLanguageTag("zh-Hant-CN-x-private"))
+	require.Equal(t, "zh-Hant", shared.TruncateLanguageTag("zh-Hant-CN"))
+	require.Equal(t, "zh", shared.TruncateLanguageTag("zh-Hant"))
+	require.Empty(t, shared.TruncateLanguageTag("zh"))
+}
+
+func TestLocalizedTextJSON(t *testing.T) {
+	type product struct {
+		Name        shared.LocalizedText   `json:"name"`
+		DisplayName shared.LocalizedString `json:"displayName"`
+	}
+	name := shared.LocalizedText{"en": "Tea", "th": "ชา"}
+
+	b, err := json.Marshal(product{Name: name, DisplayName: name.Collapse("th-TH")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":{"en":"Tea","th":"ชา"},"displayName":"ชา"}`, string(b))
+
+	b, err = json.Marshal(shared.LocalizedString{})
+	require.NoError(t, err)
+	require.Equal(t, "null", string(b))
+
+	var got shared.LocalizedText
+	require.NoError(t, json.Unmarshal([]byte(`{"en":"Tea","th":"ชา"}`), &got))
+	require.Equal(t, name, got)
+	require.NoError(t, json.Unmarshal([]byte(`"Tea"`), &got))
+	require.Equal(t, shared.LocalizedText{"en": "Tea"}, got)
+	require.Error(t, json.Unmarshal([]byte(`42`), &got))
+}
+
+func TestLocalizedTextSQL(t *testing.T) {
+	name := shared.LocalizedText{"en": "Tea", "th": "ชา"}
+	value, err := name.Value()
+	require.NoError(t, err)
+
+	var got shared.LocalizedText
+	require.NoError(t, got.Scan(value))
+	require.Equal(t, name, got)
+	require.NoError(t, got.Scan(`{"en":"Coffee"}`))
+	require.Equal(t, shared.LocalizedText{"en": "Coffee"}, got)
+	require.NoError(t, got.Scan(nil))
+	require.Nil(t, got)
+	require.Error(t, got.Scan(42))
+
+	value, err = shared.LocalizedText(nil).Value()
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestLocalizedTextValidation(t *testing.T) {
+	type product struct {
+		Name shared.LocalizedText `validate:"languages=en th"`
+	}
+
+	require.NoError(t, shared.Validator().Struct(product{Name: shared.LocalizedText{"en": "Tea", "th": "ชา"}}))
+
+	err := shared.Validator().Struct(product{Name: shared.LocalizedText{"en": "Tea", "th": ""}})
+	var errs validator.ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	require.Equal(t, []string{"name must have a text in en, th"}, shared.MapValidationErrors(&errs))
+
+	require.Error(t, shared.Validator().Struct(product{}))
+}
@@ -10,13 +10,26 @@ type ListPage[T interface{}] struct {
 	Items []T `json:"items"`
 }
 
-// TF is this
+// ErrorResponse is the JSON representation of an Error.
 type ErrorResponse struct {
 	Code  string `json:"code"`
 	Error string `json:"error"`
 }
 
+// Deprecated: use LocalizedText, TranslationText.Localized converts existing values.
 type TranslationText struct {
 	En string `json:"en"`
 	Th string `json:"th"`
 }
+
+// Localized returns the non-empty texts of t as a LocalizedText.
+func (t TranslationText) Localized() LocalizedText {
+	text := LocalizedText{}
+	if t.En != "" {
+		text["en"] = t.En
+	}
+	if t.Th != "" {
+		text["th"] = t.Th
+	}
+	return text
+}
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"reflect"
 	"strings"
 	"sync"
 
@@ -10,44 +11,231 @@ import (
 
 //nolint:gochecknoglobals // sync.OnceValue is safe for concurrent use by multiple goroutines.
 var Validator = sync.OnceValue(func() *validator.Validate {
-	return validator.New()
+	v := validator.New()
+	if err := RegisterValidations(v); err != nil {
+		panic(err)
+	}
+	return v
 })
 
+// RegisterValidations registers the validations of this package on v, e.g. on the
+// engine of gin binding: languages (see LocalizedText). Fields are named by their
+// json tag in errors, see ValidationDetails.
+func RegisterValidations(v *validator.Validate) error {
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v.RegisterValidation("languages", validateLanguages)
+}
+
+// jsonFieldName returns the name of field in JSON, empty to use its Go name.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+//nolint:gochecknoglobals // guards validationMessages.
+var validationMessagesMu sync.RWMutex
+
+// RegisterValidationMessage sets the message of the validation tag, by language.
+// {param} in messages is replaced by the param of the tag.
+func RegisterValidationMessage(tag string, messages LocalizedText) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+	validationMessages[tag] = messages
+}
+
+// ValidationDetail describes the validation error of a field.
+type ValidationDetail struct {
+	// Field is the path of the field, e.g. address.zip or items[0].name.
+	// Fields are named by their json tag, or their Go name in lower camel case.
+	Field string `json:"field"`
+	// JSONPointer is the RFC 6901 pointer to the field in the request body, e.g. /items/0/name.
+	JSONPointer string `json:"jsonPointer"`
+	// Rule is the validator tag that failed, e.g. max.
+	Rule string `json:"rule"`
+	// Param is the param of the tag, e.g. 5 for max=5.
+	Param string `json:"param,omitempty"`
+	// Message explains the error in the requested language, e.g. "must be at most 5".
+	Message string `json:"message"`
+}
+
+// String returns the field followed by the message.
+func (d ValidationDetail) String() string {
+	return d.Field + " " + d.Message
+}
+
+// ValidationDetails describes errs in language (see LocalizedText.Get for the fallbacks),
+// usually LanguageFromContext. Every validator built-in tag has a message in en and th,
+// other tags need RegisterValidationMessage.
+//
 // todo investigate using json schema for validation
 // https://github.com/santhosh-tekuri/jsonschema
+func ValidationDetails(errs validator.ValidationErrors, language string) []ValidationDetail {
+	details := make([]ValidationDetail, len(errs))
+	for i, err := range errs {
+		path := fieldPath(err.Namespace(), err.StructNamespace())
+		details[i] = ValidationDetail{
+			Field:       strings.Join(path, "."),
+			JSONPointer: jsonPointer(path),
+			Rule:        err.Tag(),
+			Param:       err.Param(),
+			Message:     validationMessage(err, language),
+		}
+	}
+	return details
+}
+
+// NewValidationDetail describes the error of field (a dotted path, e.g. address.zip)
+// breaking rule, with the message of rule in language.
+func NewValidationDetail(field, rule, param, language string) ValidationDetail {
+	return NewValidationDetailOf(reflect.Invalid, field, rule, param, language)
+}
+
+// NewValidationDetailOf is NewValidationDetail for a value of kind, for the rules
+// with a message by kind: min of a string is its length, of a slice its number of items.
+func NewValidationDetailOf(kind reflect.Kind, field, rule, param, language string) ValidationDetail {
+	path := strings.Split(field, ".")
+	if field == "" {
+		path = nil
+	}
+	return ValidationDetail{
+		Field:       field,
+		JSONPointer: jsonPointer(path),
+		Rule:        rule,
+		Param:       param,
+		Message:     lookupValidationMessage([]string{rule}, kind, param, language),
+	}
+}
+
+// ValidationError is a validation error described by its details, for errors
+// not found by the validator (e.g. a malformed request body). It matches ErrValidationFailed.
+type ValidationError struct {
+	Details []ValidationDetail
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, len(err.Details))
+	for i, detail := range err.Details {
+		messages[i] = detail.String()
+	}
+	return ErrValidationFailed.Error() + ": " + strings.Join(messages, ", ")
+}
+
+func (err *ValidationError) Is(target error) bool {
+	return ErrValidationFailed.Is(target)
+}
+
+// MapValidationErrors describes errs in English, as "field message" strings.
+//
+// Deprecated: use ValidationDetails, clients should not parse messages.
 func MapValidationErrors(errs *validator.ValidationErrors) []string {
-	details := make([]string, len(*errs))
-	for errNum, err := range *errs {
-		path := strings.Split(err.Namespace(), ".")
-		field := make([]string, len(path)-1)
-		for i, p := range path[1:] {
-			field[i] += strcase.ToLowerCamel(p)
+	details := ValidationDetails(*errs, "en")
+	messages := make([]string, len(details))
+	for i, detail := range details {
+		messages[i] = detail.String()
+	}
+	return messages
+}
+
+// fieldPath returns the namespace of a field without its root struct. Go names,
+// that are the same in structNamespace, are converted to lower camel case.
+func fieldPath(namespace, structNamespace string) []string {
+	path := strings.Split(namespace, ".")[1:]
+	goPath := strings.Split(structNamespace, ".")[1:]
+	for i, p := range path {
+		if i < len(goPath) && p == goPath[i] {
+			path[i] = strcase.ToLowerCamel(p)
+		}
+	}
+	return path
+}
+
+// jsonPointer returns the RFC 6901 pointer of path, indexes and map keys (items[0])
+// are segments of their own (/items/0).
+func jsonPointer(path []string) string {
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, p := range path {
+		name, index, _ := strings.Cut(p, "[")
+		b.WriteString("/" + escape.Replace(name))
+		for index != "" {
+			var key string
+			key, index, _ = strings.Cut(index, "]")
+			b.WriteString("/" + escape.Replace(key))
+			index = strings.TrimPrefix(index, "[")
 		}
-		details[errNum] = strings.Join(field, ".") + " " + translateTag(err)
 	}
+	return b.String()
+}
 
-	return details
+func validationMessage(err validator.FieldError, language string) string {
+	// aliases (iscolor) are tried before the tag they expanded to (hexcolor)
+	return lookupValidationMessage([]string{err.Tag(), err.ActualTag()}, err.Kind(), err.Param(), language)
 }
 
-func translateTag(err validator.FieldError) string {
-	switch err.ActualTag() {
-	case "gtfield":
-		return "must greater than " + strcase.ToLowerCamel(err.Param())
-	case "gtefield":
-		return "must be greater than or equal " + strcase.ToLowerCamel(err.Param())
-	case "lte":
-		return "must be maximum " + err.Param()
-	case "gt":
-		return "must be greater than " + err.Param()
-	case "gte":
-		return "must be greater than or equal " + err.Param()
-	case "ltefield":
-		return "must be less then or equal " + strcase.ToLowerCamel(err.Param())
-	case "required_with":
-		return "required with " + strcase.ToLowerCamel(err.Param())
-	case "oneof":
-		return "must be one of " + err.Param()
+// lookupValidationMessage returns the message of the first of tags with a message,
+// the variant of the kind of the field first.
+func lookupValidationMessage(tags []string, kind reflect.Kind, param, language string) string {
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+
+	variant := ""
+	switch kind {
+	case reflect.String:
+		variant = ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		variant = ".items"
+	default:
+	}
+
+	for _, tag := range tags {
+		if messages, ok := validationMessages[tag+variant]; ok {
+			return formatValidationMessage(messages.Get(language), validationParam(tag, param))
+		}
+		if messages, ok := validationMessages[tag]; ok {
+			return formatValidationMessage(messages.Get(language), validationParam(tag, param))
+		}
+		if format, ok := validationFormats[tag]; ok {
+			return formatValidationMessage(validationFormatMessage.Get(language), format.Get(language))
+		}
+	}
+	return invalidMessage.Get(language)
+}
+
+//nolint:gochecknoglobals // read-only catalog.
+var invalidMessage = LocalizedText{"en": "is invalid", "th": "ไม่ถูกต้อง"}
+
+func formatValidationMessage(message, param string) string {
+	return strings.ReplaceAll(message, "{param}", param)
+}
+
+// validationParam formats the param of tag for messages: field names
+// in lower camel case and lists separated by commas.
+func validationParam(tag, param string) string {
+	switch tag {
+	case "required_if", "required_unless", "skip_unless", "excluded_if", "excluded_unless":
+		// pairs of field and value
+		fields := strings.Fields(param)
+		conditions := make([]string, 0, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			conditions = append(conditions, strcase.ToLowerCamel(fields[i])+" = "+fields[i+1])
+		}
+		return strings.Join(conditions, ", ")
+	case "required_with", "required_with_all", "required_without", "required_without_all",
+		"excluded_with", "excluded_with_all", "excluded_without", "excluded_without_all",
+		"eqfield", "eqcsfield", "nefield", "necsfield", "gtfield", "gtcsfield", "gtefield", "gtecsfield",
+		"ltfield", "ltcsfield", "ltefield", "ltecsfield", "fieldcontains", "fieldexcludes",
+		"postcode_iso3166_alpha2_field":
+		fields := strings.Fields(param)
+		for i, field := range fields {
+			fields[i] = strings.Join(fieldPath("."+field, "."+field), ".")
+		}
+		return strings.Join(fields, ", ")
+	case "oneof", "oneofci", "languages":
+		return strings.Join(strings.Fields(param), ", ")
 	default:
-		return err.ActualTag()
+		return param
 	}
 }
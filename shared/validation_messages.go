@@ -0,0 +1,237 @@
+package shared
+
+// validationMessages are the message templates of the validator built-in tags by language,
+// {param} is replaced by the param of the tag. Size tags have variants for strings (.string)
+// and slices, arrays and maps (.items).
+//
+//nolint:gochecknoglobals // read-only catalog, extended by RegisterValidationMessage.
+var validationMessages = map[string]LocalizedText{
+	// presence
+	"required":             {"en": "is required", "th": "จำเป็นต้องระบุ"},
+	"required_if":          {"en": "is required when {param}", "th": "จำเป็นต้องระบุเมื่อ {param}"},
+	"required_unless":      {"en": "is required unless {param}", "th": "จำเป็นต้องระบุ ยกเว้นเมื่อ {param}"},
+	"skip_unless":          {"en": "is required unless {param}", "th": "จำเป็นต้องระบุ ยกเว้นเมื่อ {param}"},
+	"required_with":        {"en": "is required when {param} is present", "th": "จำเป็นต้องระบุเมื่อมี {param}"},
+	"required_with_all":    {"en": "is required when all of {param} are present", "th": "จำเป็นต้องระบุเมื่อมี {param} ครบทั้งหมด"},
+	"required_without":     {"en": "is required when {param} is missing", "th": "จำเป็นต้องระบุเมื่อไม่มี {param}"},
+	"required_without_all": {"en": "is required when none of {param} are present", "th": "จำเป็นต้องระบุเมื่อไม่มี {param} ทั้งหมด"},
+	"excluded_if":          {"en": "must be empty when {param}", "th": "ต้องเว้นว่างเมื่อ {param}"},
+	"excluded_unless":      {"en": "must be empty unless {param}", "th": "ต้องเว้นว่าง ยกเว้นเมื่อ {param}"},
+	"excluded_with":        {"en": "must be empty when {param} is present", "th": "ต้องเว้นว่างเมื่อมี {param}"},
+	"excluded_with_all":    {"en": "must be empty when all of {param} are present", "th": "ต้องเว้นว่างเมื่อมี {param} ครบทั้งหมด"},
+	"excluded_without":     {"en": "must be empty when {param} is missing", "th": "ต้องเว้นว่างเมื่อไม่มี {param}"},
+	"excluded_without_all": {"en": "must be empty when none of {param} are present", "th": "ต้องเว้นว่างเมื่อไม่มี {param} ทั้งหมด"},
+	"isdefault":            {"en": "must be empty", "th": "ต้องเว้นว่าง"},
+
+	// sizes and comparisons
+	"len":            {"en": "must be equal to {param}", "th": "ต้องเท่ากับ {param}"},
+	"len.string":     {"en": "must be {param} characters long", "th": "ต้องมีความยาว {param} ตัวอักษร"},
+	"len.items":      {"en": "must contain {param} items", "th": "ต้องมี {param} รายการ"},
+	"min":            {"en": "must be at least {param}", "th": "ต้องมีค่าอย่างน้อย {param}"},
+	"min.string":     {"en": "must be at least {param} characters long", "th": "ต้องมีความยาวอย่างน้อย {param} ตัวอักษร"},
+	"min.items":      {"en": "must contain at least {param} items", "th": "ต้องมีอย่างน้อย {param} รายการ"},
+	"max":            {"en": "must be at most {param}", "th": "ต้องมีค่าไม่เกิน {param}"},
+	"max.string":     {"en": "must be at most {param} characters long", "th": "ต้องมีความยาวไม่เกิน {param} ตัวอักษร"},
+	"max.items":      {"en": "must contain at most {param} items", "th": "ต้องมีไม่เกิน {param} รายการ"},
+	"eq":             {"en": "must be equal to {param}", "th": "ต้องเท่ากับ {param}"},
+	"eq.string":      {"en": "must be {param}", "th": "ต้องเป็น {param}"},
+	"eq.items":       {"en": "must contain {param} items", "th": "ต้องมี {param} รายการ"},
+	"ne":             {"en": "must not be equal to {param}", "th": "ต้องไม่เท่ากับ {param}"},
+	"ne.string":      {"en": "must not be {param}", "th": "ต้องไม่เป็น {param}"},
+	"ne.items":       {"en": "must not contain {param} items", "th": "ต้องไม่มี {param} รายการ"},
+	"lt":             {"en": "must be less than {param}", "th": "ต้องน้อยกว่า {param}"},
+	"lt.string":      {"en": "must be less than {param} characters long", "th": "ต้องมีความยาวน้อยกว่า {param} ตัวอักษร"},
+	"lt.items":       {"en": "must contain less than {param} items", "th": "ต้องมีน้อยกว่า {param} รายการ"},
+	"lte":            {"en": "must be less than or equal to {param}", "th": "ต้องน้อยกว่าหรือเท่ากับ {param}"},
+	"lte.string":     {"en": "must be at most {param} characters long", "th": "ต้องมีความยาวไม่เกิน {param} ตัวอักษร"},
+	"lte.items":      {"en": "must contain at most {param} items", "th": "ต้องมีไม่เกิน {param} รายการ"},
+	"gt":             {"en": "must be greater than {param}", "th": "ต้องมากกว่า {param}"},
+	"gt.string":      {"en": "must be more than {param} characters long", "th": "ต้องมีความยาวมากกว่า {param} ตัวอักษร"},
+	"gt.items":       {"en": "must contain more than {param} items", "th": "ต้องมีมากกว่า {param} รายการ"},
+	"gte":            {"en": "must be greater than or equal to {param}", "th": "ต้องมากกว่าหรือเท่ากับ {param}"},
+	"gte.string":     {"en": "must be at least {param} characters long", "th": "ต้องมีความยาวอย่างน้อย {param} ตัวอักษร"},
+	"gte.items":      {"en": "must contain at least {param} items", "th": "ต้องมีอย่างน้อย {param} รายการ"},
+	"eq_ignore_case": {"en": "must be {param}", "th": "ต้องเป็น {param}"},
+	"ne_ignore_case": {"en": "must not be {param}", "th": "ต้องไม่เป็น {param}"},
+	"oneof":          {"en": "must be one of {param}", "th": "ต้องเป็นค่าใดค่าหนึ่งใน {param}"},
+	"oneofci":        {"en": "must be one of {param}", "th": "ต้องเป็นค่าใดค่าหนึ่งใน {param}"},
+	"unique":         {"en": "must contain unique values", "th": "ต้องมีค่าไม่ซ้ำกัน"},
+
+	// other fields
+	"eqfield":       {"en": "must be equal to {param}", "th": "ต้องเท่ากับ {param}"},
+	"eqcsfield":     {"en": "must be equal to {param}", "th": "ต้องเท่ากับ {param}"},
+	"nefield":       {"en": "must not be equal to {param}", "th": "ต้องไม่เท่ากับ {param}"},
+	"necsfield":     {"en": "must not be equal to {param}", "th": "ต้องไม่เท่ากับ {param}"},
+	"gtfield":       {"en": "must be greater than {param}", "th": "ต้องมากกว่า {param}"},
+	"gtcsfield":     {"en": "must be greater than {param}", "th": "ต้องมากกว่า {param}"},
+	"gtefield":      {"en": "must be greater than or equal to {param}", "th": "ต้องมากกว่าหรือเท่ากับ {param}"},
+	"gtecsfield":    {"en": "must be greater than or equal to {param}", "th": "ต้องมากกว่าหรือเท่ากับ {param}"},
+	"ltfield":       {"en": "must be less than {param}", "th": "ต้องน้อยกว่า {param}"},
+	"ltcsfield":     {"en": "must be less than {param}", "th": "ต้องน้อยกว่า {param}"},
+	"ltefield":      {"en": "must be less than or equal to {param}", "th": "ต้องน้อยกว่าหรือเท่ากับ {param}"},
+	"ltecsfield":    {"en": "must be less than or equal to {param}", "th": "ต้องน้อยกว่าหรือเท่ากับ {param}"},
+	"fieldcontains": {"en": "must contain {param}", "th": "ต้องมี {param}"},
+	"fieldexcludes": {"en": "must not contain {param}", "th": "ต้องไม่มี {param}"},
+
+	// strings
+	"contains":        {"en": "must contain '{param}'", "th": "ต้องมี '{param}'"},
+	"containsany":     {"en": "must contain at least one of '{param}'", "th": "ต้องมีอักขระอย่างน้อยหนึ่งตัวใน '{param}'"},
+	"containsrune":    {"en": "must contain '{param}'", "th": "ต้องมี '{param}'"},
+	"excludes":        {"en": "must not contain '{param}'", "th": "ต้องไม่มี '{param}'"},
+	"excludesall":     {"en": "must not contain any of '{param}'", "th": "ต้องไม่มีอักขระใดใน '{param}'"},
+	"excludesrune":    {"en": "must not contain '{param}'", "th": "ต้องไม่มี '{param}'"},
+	"startswith":      {"en": "must start with '{param}'", "th": "ต้องขึ้นต้นด้วย '{param}'"},
+	"endswith":        {"en": "must end with '{param}'", "th": "ต้องลงท้ายด้วย '{param}'"},
+	"startsnotwith":   {"en": "must not start with '{param}'", "th": "ต้องไม่ขึ้นต้นด้วย '{param}'"},
+	"endsnotwith":     {"en": "must not end with '{param}'", "th": "ต้องไม่ลงท้ายด้วย '{param}'"},
+	"alpha":           {"en": "must contain only letters", "th": "ต้องเป็นตัวอักษรภาษาอังกฤษเท่านั้น"},
+	"alphanum":        {"en": "must contain only letters and digits", "th": "ต้องเป็นตัวอักษรภาษาอังกฤษหรือตัวเลขเท่านั้น"},
+	"alphaunicode":    {"en": "must contain only letters", "th": "ต้องเป็นตัวอักษรเท่านั้น"},
+	"alphanumunicode": {"en": "must contain only letters and digits", "th": "ต้องเป็นตัวอักษรหรือตัวเลขเท่านั้น"},
+	"ascii":           {"en": "must contain only ASCII characters", "th": "ต้องเป็นอักขระ ASCII เท่านั้น"},
+	"printascii":      {"en": "must contain only printable ASCII characters", "th": "ต้องเป็นอักขระ ASCII ที่พิมพ์ได้เท่านั้น"},
+	"multibyte":       {"en": "must contain multibyte characters", "th": "ต้องมีอักขระแบบหลายไบต์"},
+	"lowercase":       {"en": "must be lowercase", "th": "ต้องเป็นตัวพิมพ์เล็ก"},
+	"uppercase":       {"en": "must be uppercase", "th": "ต้องเป็นตัวพิมพ์ใหญ่"},
+	"datetime":        {"en": "must be a date and time in the format {param}", "th": "ต้องเป็นวันที่และเวลาในรูปแบบ {param}"},
+	"postcode_iso3166_alpha2": {
+		"en": "must be a valid postcode of {param}", "th": "ต้องเป็นรหัสไปรษณีย์ของ {param} ที่ถูกต้อง",
+	},
+	"postcode_iso3166_alpha2_field": {
+		"en": "must be a valid postcode of the country of {param}", "th": "ต้องเป็นรหัสไปรษณีย์ของประเทศใน {param} ที่ถูกต้อง",
+	},
+
+	// files
+	"file":     {"en": "must be an existing file", "th": "ต้องเป็นไฟล์ที่มีอยู่"},
+	"filepath": {"en": "must be a valid file path", "th": "ต้องเป็นพาธของไฟล์ที่ถูกต้อง"},
+	"dir":      {"en": "must be an existing directory", "th": "ต้องเป็นไดเรกทอรีที่มีอยู่"},
+	"dirpath":  {"en": "must be a valid directory path", "th": "ต้องเป็นพาธของไดเรกทอรีที่ถูกต้อง"},
+	"image":    {"en": "must be an image file", "th": "ต้องเป็นไฟล์รูปภาพ"},
+
+	// tags of this package
+	"languages": {"en": "must have a text in {param}", "th": "ต้องมีข้อความในภาษา {param}"},
+
+	// decoding errors, see NewValidationDetail
+	"type":    {"en": "must be of type {param}", "th": "ต้องเป็นชนิด {param}"},
+	"unknown": {"en": "is not allowed", "th": "ไม่อนุญาตให้ระบุ"},
+	"syntax":  {"en": "must be valid {param}", "th": "ต้องเป็น {param} ที่ถูกต้อง"},
+
+	// json schema keywords without a validator tag, see NewValidationDetail
+	"format":     {"en": "must be a valid {param}", "th": "ต้องเป็น {param} ที่ถูกต้อง"},
+	"pattern":    {"en": "must match the pattern {param}", "th": "ต้องตรงกับรูปแบบ {param}"},
+	"multipleOf": {"en": "must be a multiple of {param}", "th": "ต้องเป็นพหุคูณของ {param}"},
+}
+
+// validationFormats are the names of the formats checked by the validator built-in tags,
+// messages are "must be a valid <name>" (en) and "ต้องเป็น <name> ที่ถูกต้อง" (th).
+//
+//nolint:gochecknoglobals // read-only catalog.
+var validationFormats = map[string]LocalizedText{
+	"boolean":                    {"en": "boolean", "th": "ค่าบูลีน"},
+	"numeric":                    {"en": "number", "th": "ตัวเลข"},
+	"number":                     {"en": "number", "th": "ตัวเลข"},
+	"hexadecimal":                {"en": "hexadecimal number", "th": "เลขฐานสิบหก"},
+	"hexcolor":                   {"en": "hex color", "th": "รหัสสีแบบ hex"},
+	"rgb":                        {"en": "RGB color", "th": "รหัสสีแบบ RGB"},
+	"rgba":                       {"en": "RGBA color", "th": "รหัสสีแบบ RGBA"},
+	"hsl":                        {"en": "HSL color", "th": "รหัสสีแบบ HSL"},
+	"hsla":                       {"en": "HSLA color", "th": "รหัสสีแบบ HSLA"},
+	"iscolor":                    {"en": "color", "th": "รหัสสี"},
+	"e164":                       {"en": "E.164 phone number", "th": "หมายเลขโทรศัพท์รูปแบบ E.164"},
+	"email":                      {"en": "email address", "th": "อีเมล"},
+	"url":                        {"en": "URL", "th": "URL"},
+	"http_url":                   {"en": "HTTP URL", "th": "URL แบบ HTTP"},
+	"uri":                        {"en": "URI", "th": "URI"},
+	"urn_rfc2141":                {"en": "URN", "th": "URN"},
+	"base32":                     {"en": "base32 string", "th": "ข้อความ base32"},
+	"base64":                     {"en": "base64 string", "th": "ข้อความ base64"},
+	"base64url":                  {"en": "base64url string", "th": "ข้อความ base64url"},
+	"base64rawurl":               {"en": "base64url string", "th": "ข้อความ base64url"},
+	"isbn":                       {"en": "ISBN"},
+	"isbn10":                     {"en": "ISBN-10"},
+	"isbn13":                     {"en": "ISBN-13"},
+	"issn":                       {"en": "ISSN"},
+	"eth_addr":                   {"en": "Ethereum address", "th": "ที่อยู่ Ethereum"},
+	"eth_addr_checksum":          {"en": "Ethereum address", "th": "ที่อยู่ Ethereum"},
+	"btc_addr":                   {"en": "Bitcoin address", "th": "ที่อยู่ Bitcoin"},
+	"btc_addr_bech32":            {"en": "Bitcoin address", "th": "ที่อยู่ Bitcoin"},
+	"uuid":                       {"en": "UUID"},
+	"uuid3":                      {"en": "UUID v3"},
+	"uuid4":                      {"en": "UUID v4"},
+	"uuid5":                      {"en": "UUID v5"},
+	"uuid_rfc4122":               {"en": "UUID"},
+	"uuid3_rfc4122":              {"en": "UUID v3"},
+	"uuid4_rfc4122":              {"en": "UUID v4"},
+	"uuid5_rfc4122":              {"en": "UUID v5"},
+	"ulid":                       {"en": "ULID"},
+	"md4":                        {"en": "MD4 hash", "th": "ค่าแฮช MD4"},
+	"md5":                        {"en": "MD5 hash", "th": "ค่าแฮช MD5"},
+	"sha256":                     {"en": "SHA-256 hash", "th": "ค่าแฮช SHA-256"},
+	"sha384":                     {"en": "SHA-384 hash", "th": "ค่าแฮช SHA-384"},
+	"sha512":                     {"en": "SHA-512 hash", "th": "ค่าแฮช SHA-512"},
+	"ripemd128":                  {"en": "RIPEMD-128 hash", "th": "ค่าแฮช RIPEMD-128"},
+	"ripemd160":                  {"en": "RIPEMD-160 hash", "th": "ค่าแฮช RIPEMD-160"},
+	"tiger128":                   {"en": "Tiger-128 hash", "th": "ค่าแฮช Tiger-128"},
+	"tiger160":                   {"en": "Tiger-160 hash", "th": "ค่าแฮช Tiger-160"},
+	"tiger192":                   {"en": "Tiger-192 hash", "th": "ค่าแฮช Tiger-192"},
+	"datauri":                    {"en": "data URI", "th": "data URI"},
+	"latitude":                   {"en": "latitude", "th": "ละติจูด"},
+	"longitude":                  {"en": "longitude", "th": "ลองจิจูด"},
+	"ssn":                        {"en": "social security number", "th": "หมายเลขประกันสังคมสหรัฐฯ"},
+	"ipv4":                       {"en": "IPv4 address", "th": "ที่อยู่ IPv4"},
+	"ipv6":                       {"en": "IPv6 address", "th": "ที่อยู่ IPv6"},
+	"ip":                         {"en": "IP address", "th": "ที่อยู่ IP"},
+	"cidrv4":                     {"en": "IPv4 CIDR", "th": "CIDR แบบ IPv4"},
+	"cidrv6":                     {"en": "IPv6 CIDR", "th": "CIDR แบบ IPv6"},
+	"cidr":                       {"en": "CIDR", "th": "CIDR"},
+	"tcp4_addr":                  {"en": "TCPv4 address", "th": "ที่อยู่ TCPv4"},
+	"tcp6_addr":                  {"en": "TCPv6 address", "th": "ที่อยู่ TCPv6"},
+	"tcp_addr":                   {"en": "TCP address", "th": "ที่อยู่ TCP"},
+	"udp4_addr":                  {"en": "UDPv4 address", "th": "ที่อยู่ UDPv4"},
+	"udp6_addr":                  {"en": "UDPv6 address", "th": "ที่อยู่ UDPv6"},
+	"udp_addr":                   {"en": "UDP address", "th": "ที่อยู่ UDP"},
+	"ip4_addr":                   {"en": "IPv4 address", "th": "ที่อยู่ IPv4"},
+	"ip6_addr":                   {"en": "IPv6 address", "th": "ที่อยู่ IPv6"},
+	"ip_addr":                    {"en": "IP address", "th": "ที่อยู่ IP"},
+	"unix_addr":                  {"en": "Unix socket address", "th": "ที่อยู่ Unix socket"},
+	"mac":                        {"en": "MAC address", "th": "ที่อยู่ MAC"},
+	"hostname":                   {"en": "hostname", "th": "ชื่อโฮสต์"},
+	"hostname_rfc1123":           {"en": "hostname", "th": "ชื่อโฮสต์"},
+	"hostname_port":              {"en": "host and port", "th": "โฮสต์และพอร์ต"},
+	"fqdn":                       {"en": "fully qualified domain name", "th": "ชื่อโดเมนแบบเต็ม"},
+	"port":                       {"en": "port", "th": "หมายเลขพอร์ต"},
+	"html":                       {"en": "HTML", "th": "HTML"},
+	"html_encoded":               {"en": "HTML encoded string", "th": "ข้อความที่เข้ารหัสแบบ HTML"},
+	"url_encoded":                {"en": "URL encoded string", "th": "ข้อความที่เข้ารหัสแบบ URL"},
+	"json":                       {"en": "JSON", "th": "JSON"},
+	"jwt":                        {"en": "JWT", "th": "JWT"},
+	"timezone":                   {"en": "time zone", "th": "เขตเวลา"},
+	"iso3166_1_alpha2":           {"en": "country code", "th": "รหัสประเทศ"},
+	"iso3166_1_alpha2_eu":        {"en": "EU country code", "th": "รหัสประเทศในสหภาพยุโรป"},
+	"iso3166_1_alpha3":           {"en": "country code", "th": "รหัสประเทศ"},
+	"iso3166_1_alpha3_eu":        {"en": "EU country code", "th": "รหัสประเทศในสหภาพยุโรป"},
+	"iso3166_1_alpha_numeric":    {"en": "country code", "th": "รหัสประเทศ"},
+	"iso3166_1_alpha_numeric_eu": {"en": "EU country code", "th": "รหัสประเทศในสหภาพยุโรป"},
+	"country_code":               {"en": "country code", "th": "รหัสประเทศ"},
+	"eu_country_code":            {"en": "EU country code", "th": "รหัสประเทศในสหภาพยุโรป"},
+	"iso3166_2":                  {"en": "subdivision code", "th": "รหัสเขตการปกครอง"},
+	"iso4217":                    {"en": "currency code", "th": "รหัสสกุลเงิน"},
+	"iso4217_numeric":            {"en": "currency code", "th": "รหัสสกุลเงิน"},
+	"bcp47_language_tag":         {"en": "language tag", "th": "รหัสภาษา"},
+	"bic":                        {"en": "BIC", "th": "รหัส BIC"},
+	"semver":                     {"en": "semantic version", "th": "หมายเลขเวอร์ชันแบบ semver"},
+	"dns_rfc1035_label":          {"en": "DNS label", "th": "ชื่อ DNS"},
+	"credit_card":                {"en": "credit card number", "th": "หมายเลขบัตรเครดิต"},
+	"cve":                        {"en": "CVE identifier", "th": "รหัส CVE"},
+	"luhn_checksum":              {"en": "number (Luhn checksum)", "th": "ตัวเลข (Luhn checksum)"},
+	"mongodb":                    {"en": "MongoDB ObjectID", "th": "MongoDB ObjectID"},
+	"mongodb_connection_string":  {"en": "MongoDB connection string", "th": "MongoDB connection string"},
+	"cron":                       {"en": "cron expression", "th": "รูปแบบ cron"},
+	"spicedb":                    {"en": "SpiceDB identifier", "th": "รหัส SpiceDB"},
+}
+
+// validationFormatMessage is the message template of the validationFormats,
+// {param} is replaced by the name of the format.
+//
+//nolint:gochecknoglobals // read-only catalog.
+var validationFormatMessage = LocalizedText{"en": "must be a valid {param}", "th": "ต้องเป็น {param} ที่ถูกต้อง"}
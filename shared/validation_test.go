@@ -0,0 +1,130 @@
+package shared_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Lysoul/gocommon/shared"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	Zip string `json:"zip_code" validate:"len=5,numeric"`
+}
+
+type signup struct {
+	Email           string   `validate:"required,email"`
+	Password        string   `validate:"min=8"`
+	ConfirmPassword string   `validate:"eqfield=Password"`
+	Age             int      `validate:"gte=18"`
+	Tags            []string `validate:"max=2"`
+	Plan            string   `validate:"oneof=free pro"`
+	Color           string   `validate:"omitempty,iscolor"`
+	Address         address  `json:"address"`
+	Nickname        string   `validate:"omitempty,custom"`
+}
+
+func validationErrors(t *testing.T, v any) validator.ValidationErrors {
+	t.Helper()
+	var errs validator.ValidationErrors
+	require.ErrorAs(t, shared.Validator().Struct(v), &errs)
+	return errs
+}
+
+func TestValidationDetails(t *testing.T) {
+	require.NoError(t, shared.Validator().RegisterValidation("custom", func(validator.FieldLevel) bool { return false }))
+	errs := validationErrors(t, signup{
+		Password:        "short",
+		ConfirmPassword: "other",
+		Age:             16,
+		Tags:            []string{"a", "b", "c"},
+		Plan:            "gold",
+		Color:           "blue",
+		Address:         address{Zip: "123"},
+		Nickname:        "nick",
+	})
+
+	require.Equal(t, []shared.ValidationDetail{
+		{Field: "email", JSONPointer: "/email", Rule: "required", Message: "is required"},
+		{Field: "password", JSONPointer: "/password", Rule: "min", Param: "8", Message: "must be at least 8 characters long"},
+		{Field: "confirmPassword", JSONPointer: "/confirmPassword", Rule: "eqfield", Param: "Password", Message: "must be equal to password"},
+		{Field: "age", JSONPointer: "/age", Rule: "gte", Param: "18", Message: "must be greater than or equal to 18"},
+		{Field: "tags", JSONPointer: "/tags", Rule: "max", Param: "2", Message: "must contain at most 2 items"},
+		{Field: "plan", JSONPointer: "/plan", Rule: "oneof", Param: "free pro", Message: "must be one of free, pro"},
+		{Field: "color", JSONPointer: "/color", Rule: "iscolor", Message: "must be a valid color"},
+		{Field: "address.zip_code", JSONPointer: "/address/zip_code", Rule: "len", Param: "5", Message: "must be 5 characters long"},
+		{Field: "nickname", JSONPointer: "/nickname", Rule: "custom", Message: "is invalid"},
+	}, shared.ValidationDetails(errs, "en"))
+
+	th := shared.ValidationDetails(errs, "th-TH")
+	require.Equal(t, "จำเป็นต้องระบุ", th[0].Message)
+	require.Equal(t, "ต้องมีความยาวอย่างน้อย 8 ตัวอักษร", th[1].Message)
+	require.Equal(t, "ต้องเป็น รหัสสี ที่ถูกต้อง", th[6].Message)
+	// unknown languages fall back to en
+	require.Equal(t, "is required", shared.ValidationDetails(errs, "fr")[0].Message)
+
+	shared.RegisterValidationMessage("custom", shared.LocalizedText{"en": "must be custom", "th": "ต้องกำหนดเอง"})
+	require.Equal(t, "must be custom", shared.ValidationDetails(errs, "en")[8].Message)
+	require.Equal(t, "nickname must be custom", shared.MapValidationErrors(&errs)[8])
+}
+
+func TestValidationDetailsFormats(t *testing.T) {
+	type input struct {
+		ID      string `validate:"uuid4"`
+		Site    string `validate:"url"`
+		Zip     string `validate:"required_if=Country TH"`
+		Country string
+	}
+	errs := validationErrors(t, input{ID: "x", Site: "x", Country: "TH"})
+	messages := []string{}
+	for _, detail := range shared.ValidationDetails(errs, "en") {
+		messages = append(messages, detail.String())
+	}
+	require.Equal(t, []string{
+		"id must be a valid UUID v4",
+		"site must be a valid URL",
+		"zip is required when country = TH",
+	}, messages)
+}
+
+func TestValidationDetailsPaths(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku" validate:"required"`
+	}
+	type order struct {
+		Items    []item            `json:"items" validate:"dive"`
+		Labels   map[string]string `json:"labels" validate:"dive,max=3"`
+		Internal string            `json:"-" validate:"required"`
+	}
+	errs := validationErrors(t, order{
+		Items:  []item{{SKU: "a"}, {}},
+		Labels: map[string]string{"a/b": "long"},
+	})
+
+	details := shared.ValidationDetails(errs, "en")
+	require.Len(t, details, 3)
+	require.Equal(t, "items[1].sku", details[0].Field)
+	require.Equal(t, "/items/1/sku", details[0].JSONPointer)
+	require.Equal(t, "labels[a/b]", details[1].Field)
+	require.Equal(t, "/labels/a~1b", details[1].JSONPointer)
+	require.Equal(t, "internal", details[2].Field)
+}
+
+func TestNewValidationDetail(t *testing.T) {
+	require.Equal(t, shared.ValidationDetail{
+		Field:       "items[0].sku",
+		JSONPointer: "/items/0/sku",
+		Rule:        "required",
+		Message:     "is required",
+	}, shared.NewValidationDetail("items[0].sku", "required", "", "en"))
+	require.Equal(t, "must be at least 3", shared.NewValidationDetail("name", "min", "3", "en").Message)
+	require.Equal(t, "must be one of a, b", shared.NewValidationDetail("plan", "oneof", "a b", "en").Message)
+	require.Equal(t, "must be a valid email address", shared.NewValidationDetail("email", "email", "", "en").Message)
+	require.Equal(t, "is invalid", shared.NewValidationDetail("name", "unknown_rule", "", "en").Message)
+
+	require.Equal(t, "must be at least 3 characters long",
+		shared.NewValidationDetailOf(reflect.String, "name", "min", "3", "en").Message)
+	require.Equal(t, "ต้องตรงกับรูปแบบ ^a",
+		shared.NewValidationDetailOf(reflect.String, "name", "pattern", "^a", "th").Message)
+}